@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/ruffel/godotreleaser/internal/cmd/root"
 )
@@ -21,7 +24,23 @@ func main() {
 }
 
 func mainRun() exitCode {
-	if err := root.NewRootCmd().ExecuteContext(context.Background()); err != nil {
+	// Cancel the context on Ctrl-C so that in-flight work can clean up after
+	// itself. A second signal terminates the process as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := root.NewRootCmd().ExecuteContext(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, "Cancelled")
+
+			return exitCancel
+		}
+
 		fmt.Fprintln(os.Stderr, err)
 
 		return exitError
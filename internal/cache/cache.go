@@ -0,0 +1,362 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/ruffel/godotreleaser/internal/utils/checksum"
+	"github.com/ruffel/godotreleaser/internal/utils/filelock"
+)
+
+const monoSuffix = "-mono"
+
+// Entry is a single cached toolchain, including its export templates.
+type Entry struct {
+	Version  string
+	Mono     bool
+	Platform paths.Platform
+	Dir      string
+	// TemplateDir is where the export templates of the toolchain are. Unless
+	// the manifest records them, they may have been installed by the Godot
+	// editor rather than godotreleaser, and are left alone.
+	TemplateDir string
+	Size        int64
+	LastUsed    time.Time
+	Manifest    *Manifest
+}
+
+// Toolchain returns the toolchain the entry holds.
+func (e Entry) Toolchain() paths.Toolchain {
+	return paths.Toolchain{Version: e.Version, Mono: e.Mono, Platform: e.Platform}
+}
+
+// OwnsTemplates reports whether the export templates of the entry were
+// installed by godotreleaser, and so can be removed with it.
+func (e Entry) OwnsTemplates() bool {
+	return e.Manifest != nil && e.Manifest.Templates != nil
+}
+
+// Name returns the identifier of the entry as used in the cache directory.
+func (e Entry) Name() string {
+	rel, err := filepath.Rel(paths.Cache(), e.Dir)
+	if err != nil {
+		return filepath.Base(e.Dir)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// List returns every toolchain found in the cache, most recently used first.
+// Toolchains for other platforms are found in a directory per platform.
+func List() ([]Entry, error) {
+	entries, err := listDir(paths.Cache(), paths.Host())
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+
+	return entries, nil
+}
+
+func listDir(dir string, platform paths.Platform) ([]Entry, error) {
+	dirs, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(dirs))
+
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+
+		// Only the top level holds platform directories.
+		if p, err := paths.ParsePlatform(d.Name()); err == nil && dir == paths.Cache() {
+			foreign, err := listDir(filepath.Join(dir, d.Name()), p)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, foreign...)
+
+			continue
+		}
+
+		version, mono := strings.CutSuffix(d.Name(), monoSuffix)
+
+		entry, err := newEntry(paths.Toolchain{Version: version, Mono: mono, Platform: platform})
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Find returns the cache entry of a specific toolchain.
+func Find(t paths.Toolchain) (Entry, error) {
+	if _, err := os.Stat(t.Dir()); err != nil {
+		return Entry{}, fmt.Errorf("godot %s is not installed: %w", filepath.Base(t.Dir()), err)
+	}
+
+	return newEntry(t)
+}
+
+func newEntry(t paths.Toolchain) (Entry, error) {
+	entry := Entry{
+		Version:     t.Version,
+		Mono:        t.Mono,
+		Platform:    t.Platform,
+		Dir:         t.Dir(),
+		TemplateDir: t.TemplateDir(),
+	}
+
+	manifest, err := ReadManifest(t)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Entry{}, err
+	}
+
+	entry.Manifest = manifest
+
+	if manifest != nil {
+		entry.LastUsed = manifest.LastUsed
+
+		if manifest.Templates != nil {
+			entry.TemplateDir = manifest.Templates.Path
+		}
+	} else if info, err := os.Stat(entry.Dir); err == nil {
+		entry.LastUsed = info.ModTime()
+	}
+
+	dirs := []string{entry.Dir}
+
+	// The templates of other platforms and of self-contained editors are
+	// inside the toolchain directory.
+	if entry.OwnsTemplates() && !within(entry.TemplateDir, entry.Dir) {
+		dirs = append(dirs, entry.TemplateDir)
+	}
+
+	for _, dir := range dirs {
+		size, err := dirSize(dir)
+		if err != nil {
+			return Entry{}, err
+		}
+
+		entry.Size += size
+	}
+
+	return entry, nil
+}
+
+// Remove deletes a toolchain, and its export templates if godotreleaser
+// installed them. It fails if the toolchain is currently being installed by
+// another process.
+func Remove(e Entry) error {
+	lock, err := filelock.TryAcquire(e.Toolchain().Lock())
+	if err != nil {
+		return fmt.Errorf("cannot remove %s: %w", e.Name(), err)
+	}
+	defer lock.Release() //nolint:errcheck
+
+	// The toolchain directory holds the manifest, so it goes last.
+	dirs := []string{e.Dir}
+	if e.OwnsTemplates() {
+		dirs = []string{e.TemplateDir, e.Dir}
+	}
+
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+	}
+
+	return nil
+}
+
+// SelectPrunable returns the entries that should be pruned. The keep most
+// recently used entries are always retained; of the rest, only those unused
+// for longer than olderThan are selected, unless olderThan is zero.
+//
+// The entries must be ordered most recently used first, as returned by List.
+func SelectPrunable(entries []Entry, keep int, olderThan time.Duration, now time.Time) []Entry {
+	var prunable []Entry
+
+	for i, e := range entries {
+		if i < keep {
+			continue
+		}
+
+		if olderThan > 0 && now.Sub(e.LastUsed) < olderThan {
+			continue
+		}
+
+		prunable = append(prunable, e)
+	}
+
+	return prunable
+}
+
+// Problem is a discrepancy found while verifying a toolchain.
+type Problem struct {
+	Path   string
+	Reason string
+}
+
+// Verify checks the installed files of a toolchain. The SHA-512 of each
+// archive recorded when it was installed is checked against the published
+// sums, keyed by file name, so that an archive that was already corrupt then
+// is found. Each extracted file is then checked against the SHA-256 recorded
+// when it was extracted. Without published sums, only archives that were
+// checked against them at install time pass.
+func Verify(e Entry, sums map[string]string) ([]Problem, error) {
+	if e.Manifest == nil {
+		return []Problem{{Path: e.Dir, Reason: "no install manifest, cannot verify"}}, nil
+	}
+
+	var problems []Problem
+
+	if e.Manifest.Editor == nil && e.Manifest.Templates == nil {
+		return []Problem{{Path: e.Dir, Reason: "nothing recorded in install manifest"}}, nil
+	}
+
+	// The editor may be missing when templates were installed for an existing
+	// Godot install, so only recorded components are checked.
+	for _, c := range []*Component{e.Manifest.Editor, e.Manifest.Templates} {
+		if c == nil {
+			continue
+		}
+
+		if problem, ok := verifyArchive(c, sums); !ok {
+			problems = append(problems, problem)
+		}
+
+		found, err := verifyComponent(c)
+		if err != nil {
+			return nil, err
+		}
+
+		problems = append(problems, found...)
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].Path < problems[j].Path
+	})
+
+	return problems, nil
+}
+
+// verifyArchive checks the recorded checksum of the archive a component was
+// extracted from against the published one.
+func verifyArchive(c *Component, sums map[string]string) (Problem, bool) {
+	name := path.Base(c.Source)
+
+	if c.Checksum == "" {
+		return Problem{Path: c.Path, Reason: "no archive checksum recorded"}, false
+	}
+
+	want, published := sums[name]
+
+	switch {
+	case published && want != c.Checksum:
+		return Problem{Path: c.Path, Reason: fmt.Sprintf("archive %s does not match the published checksum", name)}, false
+	case !published && !c.Verified:
+		return Problem{Path: c.Path, Reason: fmt.Sprintf("archive %s was never checked against a published checksum", name)}, false
+	}
+
+	return Problem{}, true
+}
+
+func verifyComponent(c *Component) ([]Problem, error) {
+	var problems []Problem
+
+	for rel, want := range c.Files {
+		path := filepath.Join(c.Path, filepath.FromSlash(rel))
+
+		got, err := checksum.SHA256File(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				problems = append(problems, Problem{Path: path, Reason: "missing"})
+
+				continue
+			}
+
+			return nil, err //nolint:wrapcheck
+		}
+
+		if got != want {
+			problems = append(problems, Problem{Path: path, Reason: "checksum mismatch"})
+		}
+	}
+
+	return problems, nil
+}
+
+// within reports whether path is dir or below it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// dirSize returns the total size of all files below dir, or zero if dir does
+// not exist.
+func dirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			size += info.Size()
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("failed to compute size of %s: %w", dir, err)
+	}
+
+	return size, nil
+}
+
+// FormatSize renders a byte count in human readable form.
+func FormatSize(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,167 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectPrunable(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	// Ordered most recently used first, as returned by cache.List.
+	entries := []cache.Entry{
+		{Version: "4.3", LastUsed: now.Add(-1 * day)},
+		{Version: "4.2.2", LastUsed: now.Add(-10 * day)},
+		{Version: "4.2.1", LastUsed: now.Add(-40 * day)},
+		{Version: "4.1", LastUsed: now.Add(-90 * day)},
+	}
+
+	tests := []struct {
+		name      string
+		keep      int
+		olderThan time.Duration
+		want      []string
+	}{
+		{name: "keep only", keep: 2, want: []string{"4.2.1", "4.1"}},
+		{name: "older than only", olderThan: 30 * day, want: []string{"4.2.1", "4.1"}},
+		{name: "keep wins over age", keep: 3, olderThan: 30 * day, want: []string{"4.1"}},
+		{name: "keep everything", keep: 10, want: []string{}},
+		{name: "nothing old enough", olderThan: 365 * day, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := cache.SelectPrunable(entries, tt.keep, tt.olderThan, now)
+
+			assert.Equal(t, tt.want, lo.Map(got, func(e cache.Entry, _ int) string { return e.Version }))
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "512 B", cache.FormatSize(512))
+	assert.Equal(t, "1.5 KiB", cache.FormatSize(1536))
+	assert.Equal(t, "1.0 GiB", cache.FormatSize(1<<30))
+}
+
+func TestList_Platforms(t *testing.T) {
+	t.Setenv(paths.EnvCacheDir, t.TempDir())
+
+	host := paths.NewToolchain("4.3", false)
+	foreign := paths.Toolchain{Version: "4.3", Mono: true, Platform: paths.Platform{OS: "windows", Arch: "amd64"}}
+
+	if host.Platform == foreign.Platform {
+		foreign.Platform.Arch = "arm64"
+	}
+
+	for _, tc := range []paths.Toolchain{host, foreign} {
+		m, err := cache.LoadOrCreateManifest(tc)
+		require.NoError(t, err)
+		require.NoError(t, m.Write())
+	}
+
+	entries, err := cache.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	byName := lo.KeyBy(entries, func(e cache.Entry) string { return e.Name() })
+
+	require.Contains(t, byName, "4.3")
+	assert.Equal(t, host, byName["4.3"].Toolchain())
+
+	name := foreign.Platform.String() + "/4.3-mono"
+	require.Contains(t, byName, name)
+	assert.Equal(t, foreign, byName[name].Toolchain())
+	assert.Equal(t, filepath.Join(foreign.Dir(), "templates"), byName[name].TemplateDir)
+}
+
+func TestRemove_KeepsEditorTemplates(t *testing.T) {
+	t.Setenv(paths.EnvCacheDir, t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	tc := paths.NewToolchain("4.3", false)
+
+	// Templates installed through the Godot editor, unknown to the manifest.
+	shared := filepath.Join(tc.TemplateDir(), "version.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(shared), 0o755))
+	require.NoError(t, os.WriteFile(shared, []byte("4.3.stable"), 0o600))
+
+	m, err := cache.LoadOrCreateManifest(tc)
+	require.NoError(t, err)
+	require.NoError(t, m.Write())
+
+	entry, err := cache.Find(tc)
+	require.NoError(t, err)
+	assert.False(t, entry.OwnsTemplates())
+	require.NoError(t, cache.Remove(entry))
+
+	assert.NoDirExists(t, tc.Dir())
+	assert.FileExists(t, shared)
+
+	// Once recorded, they were installed by godotreleaser and go too.
+	m.Templates = &cache.Component{Path: tc.TemplateDir()}
+	require.NoError(t, m.Write())
+
+	entry, err = cache.Find(tc)
+	require.NoError(t, err)
+	assert.True(t, entry.OwnsTemplates())
+	require.NoError(t, cache.Remove(entry))
+
+	assert.NoDirExists(t, tc.Dir())
+	assert.NoDirExists(t, tc.TemplateDir())
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Godot"), []byte("binary"), 0o600))
+
+	const archive = "Godot_v4.3-stable_linux.x86_64.zip"
+
+	tests := []struct {
+		name     string
+		recorded string
+		verified bool
+		sums     map[string]string
+		reasons  []string
+	}{
+		{name: "matches published", recorded: "abc", sums: map[string]string{archive: "abc"}, reasons: []string{}},
+		{name: "corrupt when installed", recorded: "abc", sums: map[string]string{archive: "def"}, reasons: []string{"archive " + archive + " does not match the published checksum"}},
+		{name: "verified at install", recorded: "abc", verified: true, reasons: []string{}},
+		{name: "never verified", recorded: "abc", reasons: []string{"archive " + archive + " was never checked against a published checksum"}},
+		{name: "no checksum", sums: map[string]string{archive: "abc"}, reasons: []string{"no archive checksum recorded"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			editor, err := cache.NewComponent(dir, "https://example.com/"+archive, tt.recorded, tt.verified)
+			require.NoError(t, err)
+
+			entry := cache.Entry{Dir: dir, Manifest: &cache.Manifest{Editor: editor}}
+
+			problems, err := cache.Verify(entry, tt.sums)
+			require.NoError(t, err)
+			assert.Equal(t, tt.reasons, lo.Map(problems, func(p cache.Problem, _ int) string { return p.Reason }))
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/ruffel/godotreleaser/internal/utils/checksum"
+	"github.com/samber/lo"
+)
+
+const manifestName = "manifest.json"
+
+// Component describes one installed part of a toolchain, either the editor
+// binary or the export templates.
+type Component struct {
+	// Path is the directory the component was extracted to.
+	Path string `json:"path"`
+	// Source is where the archive was obtained from.
+	Source string `json:"source,omitempty"`
+	// Checksum is the SHA-512 digest of the archive.
+	Checksum string `json:"checksum,omitempty"`
+	// Verified is set when the archive matched a published checksum.
+	Verified bool `json:"verified"`
+	// Partial is set when only some of the files of the archive were extracted.
+	Partial bool `json:"partial,omitempty"`
+	// InstalledAt is when the component was moved into place.
+	InstalledAt time.Time `json:"installedAt"`
+	// Files maps each extracted file, relative to Path, to its SHA-256 digest.
+	Files map[string]string `json:"files"`
+}
+
+// Installed reports whether the component is recorded and still present.
+func (c *Component) Installed() bool {
+	if c == nil {
+		return false
+	}
+
+	info, err := os.Stat(c.Path)
+
+	return err == nil && info.IsDir()
+}
+
+// NewComponent records the files currently found in dir.
+func NewComponent(dir, source, sum string, verified bool) (*Component, error) {
+	files, err := hashTree(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Component{
+		Path:        dir,
+		Source:      source,
+		Checksum:    sum,
+		Verified:    verified,
+		InstalledAt: time.Now().UTC(),
+		Files:       files,
+	}, nil
+}
+
+// FileNames returns the recorded files, relative to Path.
+func (c *Component) FileNames() []string {
+	if c == nil {
+		return nil
+	}
+
+	return lo.Keys(c.Files)
+}
+
+// Manifest is stored alongside each cached toolchain and records what was
+// installed, where it came from and when it was last used.
+//
+// The manifest doubles as the install marker: a component is only written to
+// it once it has been completely extracted, so anything on disk that is not
+// recorded in the manifest is treated as an incomplete install.
+type Manifest struct {
+	Version       string     `json:"version"`
+	Mono          bool       `json:"mono"`
+	Platform      string     `json:"platform,omitempty"` // As "<os>-<arch>".
+	SelfContained bool       `json:"selfContained,omitempty"`
+	InstalledAt   time.Time  `json:"installedAt"`
+	LastUsed      time.Time  `json:"lastUsed"`
+	Editor        *Component `json:"editor,omitempty"`
+	Templates     *Component `json:"templates,omitempty"`
+}
+
+// ManifestPath returns the location of the manifest for a toolchain.
+func ManifestPath(t paths.Toolchain) string {
+	return filepath.Join(t.Dir(), manifestName)
+}
+
+// ReadManifest loads the manifest of a toolchain. The returned error wraps
+// fs.ErrNotExist when the toolchain has no manifest.
+func ReadManifest(t paths.Toolchain) (*Manifest, error) {
+	data, err := os.ReadFile(ManifestPath(t))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	// Manifests written before platforms were recorded are for the host.
+	m.Platform = t.Platform.String()
+
+	return &m, nil
+}
+
+// LoadOrCreateManifest loads the manifest of a toolchain, or returns a new
+// empty one if none exists yet.
+func LoadOrCreateManifest(t paths.Toolchain) (*Manifest, error) {
+	m, err := ReadManifest(t)
+	if err == nil {
+		return m, nil
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	return &Manifest{
+		Version:     t.Version,
+		Mono:        t.Mono,
+		Platform:    t.Platform.String(),
+		InstalledAt: now,
+		LastUsed:    now,
+	}, nil
+}
+
+// Toolchain returns the toolchain the manifest belongs to.
+func (m *Manifest) Toolchain() paths.Toolchain {
+	t := paths.NewToolchain(m.Version, m.Mono)
+
+	if p, err := paths.ParsePlatform(m.Platform); err == nil {
+		t.Platform = p
+	}
+
+	return t
+}
+
+// Write persists the manifest into the toolchain directory.
+func (m *Manifest) Write() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	path := ManifestPath(m.Toolchain())
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o0755); err != nil {
+		return fmt.Errorf("failed to create toolchain directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o0644); err != nil { //nolint:gosec
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// Touch records that a toolchain has just been used.
+func Touch(t paths.Toolchain) error {
+	m, err := ReadManifest(t)
+	if err != nil {
+		return err
+	}
+
+	m.LastUsed = time.Now().UTC()
+
+	return m.Write()
+}
+
+// hashTree returns the SHA-256 digest of every regular file below dir.
+func hashTree(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		sum, err := checksum.SHA256File(path)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		files[filepath.ToSlash(rel)] = sum
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", dir, err)
+	}
+
+	return files, nil
+}
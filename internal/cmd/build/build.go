@@ -4,15 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
-
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/godot/engine"
+	"github.com/ruffel/godotreleaser/internal/godot/templates"
+	"github.com/ruffel/godotreleaser/internal/lockfile"
+	"github.com/ruffel/godotreleaser/internal/paths"
 	"github.com/ruffel/godotreleaser/internal/stages/builder"
 	"github.com/ruffel/godotreleaser/internal/stages/dependencies"
+	"github.com/ruffel/godotreleaser/internal/stages/stamp"
 	"github.com/ruffel/godotreleaser/internal/terminal"
 	"github.com/ruffel/godotreleaser/internal/terminal/messages"
+	"github.com/ruffel/godotreleaser/internal/utils/httpclient"
+	"github.com/ruffel/godotreleaser/pkg/godot/client"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/exports"
 	"github.com/ruffel/godotreleaser/pkg/godot/config/project"
+	"github.com/samber/lo"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 )
@@ -22,6 +35,22 @@ type buildOpts struct {
 	Version    string
 	Mono       bool
 	MonoSet    bool
+	Binary     string
+	// AllowLockMismatch builds even if the toolchain differs from the lockfile.
+	AllowLockMismatch bool
+	// MinimalTemplates only installs the export templates used by the presets.
+	MinimalTemplates bool
+	// SelfContained installs the editor in self-contained mode.
+	SelfContained bool
+	// ReleaseVersion is stamped into project.godot during the export. It
+	// defaults to the git tag of the checked out commit.
+	ReleaseVersion string
+	// StampPresets also stamps the release version into Windows presets.
+	StampPresets bool
+	// DryRun prints what would be built without downloading or exporting.
+	DryRun bool
+	// HTTP configures how the toolchain is downloaded.
+	HTTP httpclient.Config
 	// Dependencies
 	fs afero.Fs
 }
@@ -45,6 +74,14 @@ func NewBuildCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&opts.ProjectDir, "project", "p", "", "Path to the Godot project directory (defaults to the current directory)")
 	cmd.Flags().StringVarP(&opts.Version, "version", "v", "", "Godot version to use")
 	cmd.Flags().BoolVar(&opts.Mono, "with-mono", false, "Mono version of Godot")
+	cmd.Flags().BoolVar(&opts.AllowLockMismatch, "allow-lock-mismatch", false, "Build even if the Godot toolchain does not match "+lockfile.Name)
+	cmd.Flags().BoolVar(&opts.MinimalTemplates, "minimal-templates", false, "Only install the export templates needed by the presets in export_presets.cfg")
+	cmd.Flags().BoolVar(&opts.SelfContained, "self-contained", false, "Install the editor in self-contained mode, keeping its settings and export templates out of the user profile")
+	cmd.Flags().StringVar(&opts.ReleaseVersion, "release-version", "", "Version to set as application/config/version while exporting (defaults to the git tag of HEAD)")
+	cmd.Flags().BoolVar(&opts.StampPresets, "stamp-presets", false, "Also set the release version as the file and product version of Windows presets")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Print the Godot version, .NET detection and presets that would be used, without downloading or exporting")
+	cmd.Flags().StringVar(&opts.Binary, "godot-binary", "", "Path to an existing Godot binary to use instead of downloading one (or set $"+godotBinaryEnv+")")
+	opts.HTTP.AddFlags(cmd.Flags())
 
 	return cmd
 }
@@ -65,24 +102,30 @@ func runBuild(ctx context.Context, opts *buildOpts) error {
 		return fmt.Errorf("project file is not valid: %w", err)
 	}
 
+	lock, err := readLockfile(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
 	//--------------------------------------------------------------------------
 	// We need a Godot binary and export templates to build the project.
 	//
 	// Download the Godot binary and export templates if they don't exist.
 	//--------------------------------------------------------------------------
 	version := func() string {
-		if opts.Version != "" {
-			slog.Debug("Using version provided by user", "version", opts.Version)
-
-			return opts.Version
+		var locked string
+		if lock != nil {
+			locked = lock.Version
 		}
 
-		if project.EngineVersion() != nil {
-			version := project.EngineVersion().Original()
-
-			slog.Debug("Using version found in project.godot", "version", version)
+		if v, ok := engine.ResolveVersion(os.DirFS(filepath.Dir(path)), project, opts.Version, locked); ok {
+			return v.Version
+		}
 
-			return version
+		// Godot 3 projects don't say which version they need, and defaulting
+		// to Godot 4 would upgrade them.
+		if project.IsGodot3() {
+			return ""
 		}
 
 		slog.Debug("Using default version", "version", "4.3")
@@ -90,29 +133,282 @@ func runBuild(ctx context.Context, opts *buildOpts) error {
 		return "4.3" // Or error?
 	}()
 
-	useMono := func() bool {
+	useMono, monoReasons := func() (bool, []string) {
 		if opts.MonoSet {
-			return opts.Mono
+			return opts.Mono, []string{"set by --with-mono"}
+		}
+
+		if lock != nil {
+			return lock.Mono(), []string{"flavor found in " + lockfile.Name}
 		}
 
-		return project.ContainsMono()
+		return detectDotNet(ctx, filepath.Dir(path), project, opts.DryRun)
 	}()
 
-	if err := dependencies.Run(ctx, opts.fs, version, useMono); err != nil {
-		return err //nolint:wrapcheck
+	//--------------------------------------------------------------------------
+	// An existing Godot install takes precedence over downloading one. Its
+	// version decides which export templates are needed.
+	//--------------------------------------------------------------------------
+	external, err := findExternalGodot(ctx, opts.Binary, version, useMono)
+	if err != nil {
+		return err
+	}
+
+	if external != nil {
+		slog.Info("Using existing Godot install", "path", external.path, "version", external.info.Raw)
+
+		if !external.stable() {
+			slog.Warn("Godot is not a stable release, so export templates cannot be downloaded for it", "version", external.info.Raw)
+
+			if err := checkInstalledTemplates(filepath.Dir(path), external); err != nil {
+				return err
+			}
+		}
+
+		if external.info.Mono != useMono {
+			monoReasons = []string{"decided by the existing Godot install"}
+		}
+
+		version, useMono = external.info.Version, external.info.Mono
+	}
+
+	if version == "" {
+		return ErrGodot3VersionUnknown
+	}
+
+	if engine.IsGodot3(version) != project.IsGodot3() {
+		slog.Warn("Godot version does not match the project format", "version", version, "godot3Project", project.IsGodot3())
+	}
+
+	if opts.DryRun {
+		return dryRun(ctx, opts, path, external, version, useMono, monoReasons)
 	}
 
-	if err := builder.Run(ctx, opts.fs, version, useMono, path); err != nil {
+	client, err := opts.HTTP.Client()
+	if err != nil {
 		return err //nolint:wrapcheck
 	}
 
+	if external != nil && opts.SelfContained {
+		slog.Warn("Ignoring --self-contained, as an existing Godot install is used")
+	}
+
+	deps := &dependencies.Options{
+		Version:       version,
+		Mono:          useMono,
+		TemplatesOnly: external != nil,
+		SelfContained: opts.SelfContained,
+		HTTPClient:    client,
+	}
+
+	if opts.MinimalTemplates {
+		deps.TemplateFiles = requiredTemplates(filepath.Dir(path), version)
+	}
+
+	// A toolchain that can't match the lockfile isn't worth downloading.
+	if lock != nil && !opts.AllowLockMismatch {
+		if problems := lock.CheckVersion(version, useMono); len(problems) > 0 {
+			return lockMismatch(problems)
+		}
+	}
+
+	// The templates of other builds were checked for above.
+	if external == nil || external.stable() {
+		if err := dependencies.Run(ctx, opts.fs, deps); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	if err := checkLockfile(lock, version, useMono, external == nil, opts.AllowLockMismatch); err != nil {
+		return err
+	}
+
+	c, err := newClient(external, version, useMono)
+	if err != nil {
+		return err
+	}
+
+	if err := build(ctx, opts, c, path); err != nil {
+		return err
+	}
+
 	terminal.Send(messages.NewFooter("Project Built"))
 
 	return nil
 }
 
+// build exports the presets of a project, with the release version stamped into
+// it. The stamped files are restored afterwards, including when the export
+// fails or is cancelled.
+func build(ctx context.Context, opts *buildOpts, c *client.Client, path string) error {
+	release := opts.ReleaseVersion
+	if release == "" {
+		release = stamp.GitTag(ctx, filepath.Dir(path))
+	}
+
+	stamped, err := stamp.Apply(opts.fs, filepath.Dir(path), stamp.Options{Version: release, Presets: opts.StampPresets})
+	if err != nil {
+		return fmt.Errorf("failed to stamp release version: %w", err)
+	}
+
+	buildErr := builder.Run(ctx, opts.fs, c, path)
+
+	if err := stamped.Restore(); err != nil {
+		return errors.Join(buildErr, err)
+	}
+
+	return buildErr //nolint:wrapcheck
+}
+
+// detectDotNet reports whether a project uses C#, logging why. Every reason is
+// only looked for when they're printed: for a dry run, or with debug logging.
+func detectDotNet(ctx context.Context, projectDir string, config *project.Config, explain bool) (bool, []string) {
+	detect := project.DetectDotNet
+	if explain || slog.Default().Enabled(ctx, slog.LevelDebug) {
+		detect = project.ExplainDotNet
+	}
+
+	dotnet := detect(os.DirFS(projectDir), config)
+
+	for _, reason := range dotnet.Reasons {
+		slog.Debug("Detected C# project", "reason", reason)
+	}
+
+	if !dotnet.Enabled() {
+		slog.Debug("No C# project found, using the standard build of Godot")
+
+		return false, []string{"no .csproj using Godot.NET.Sdk, [dotnet] section or C# scripts found"}
+	}
+
+	return true, dotnet.Reasons
+}
+
+// dryRun prints what a build would do.
+func dryRun(ctx context.Context, opts *buildOpts, path string, external *externalGodot, version string, mono bool, monoReasons []string) error {
+	pterm.Info.Printfln("Project: %s", path)
+
+	if external != nil {
+		pterm.Info.Printfln("Godot: %s (existing install at %s)", version, external.path)
+	} else {
+		pterm.Info.Printfln("Godot: %s", version)
+	}
+
+	pterm.Info.Printfln(".NET: %s", lo.Ternary(mono, "yes", "no"))
+
+	for _, reason := range monoReasons {
+		pterm.Println("  - " + reason)
+	}
+
+	release := opts.ReleaseVersion
+	if release == "" {
+		release = stamp.GitTag(ctx, filepath.Dir(path))
+	}
+
+	if release != "" {
+		pterm.Info.Printfln("Release version: %s", release)
+	} else {
+		pterm.Info.Println("Release version: none, project.godot won't be stamped")
+	}
+
+	e, err := exports.New(filepath.Join(filepath.Dir(path), "export_presets.cfg"))
+	if err != nil {
+		return fmt.Errorf("failed to read export presets: %w", err)
+	}
+
+	for _, preset := range e.Presets() {
+		pterm.Info.Printfln("Would export %q (%s) to %s", preset.Name, preset.Platform, preset.ExportPath)
+	}
+
+	return nil
+}
+
+// requiredTemplates returns the export template files needed by the presets of
+// a project, or nil if they cannot be determined and all templates are needed.
+func requiredTemplates(projectDir string, version string) []string {
+	e, err := exports.New(filepath.Join(projectDir, "export_presets.cfg"))
+	if err != nil {
+		slog.Warn("Cannot read export presets, installing all export templates", "error", err)
+
+		return nil
+	}
+
+	files, err := templates.ForPresets(e.Presets(), version)
+	if err != nil {
+		slog.Warn("Cannot determine export templates needed, installing all export templates", "error", err)
+
+		return nil
+	}
+
+	slog.Debug("Export templates needed by presets", "files", files)
+
+	return files
+}
+
+// readLockfile loads the lockfile of a project, returning nil if the project
+// doesn't have one.
+func readLockfile(projectDir string) (*lockfile.Lockfile, error) {
+	lock, err := lockfile.Read(lockfile.Path(projectDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil //nolint:nilnil
+	}
+
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	slog.Debug("Found lockfile", "path", lockfile.Path(projectDir), "version", lock.Version, "flavor", lock.Flavor)
+
+	return lock, nil
+}
+
+// checkLockfile makes sure the toolchain about to be used is the one pinned by
+// the lockfile, if there is one. A toolchain missing from the cache, such as an
+// existing install of a build that isn't a stable release, doesn't match.
+func checkLockfile(lock *lockfile.Lockfile, version string, mono bool, checkEditor bool, allowMismatch bool) error {
+	if lock == nil {
+		return nil
+	}
+
+	var problems []string
+
+	manifest, err := cache.ReadManifest(paths.NewToolchain(version, mono))
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		problems = append(lock.CheckVersion(version, mono), fmt.Sprintf("Godot %s is not installed in the cache, so its checksums are unknown", version))
+	case err != nil:
+		return err //nolint:wrapcheck
+	default:
+		problems = lock.Check(manifest, checkEditor)
+	}
+
+	if len(problems) == 0 {
+		slog.Debug("Toolchain matches lockfile", "version", version, "mono", mono)
+
+		return nil
+	}
+
+	if allowMismatch {
+		for _, p := range problems {
+			slog.Warn("Toolchain does not match lockfile", "problem", p)
+		}
+
+		return nil
+	}
+
+	return lockMismatch(problems)
+}
+
+func lockMismatch(problems []string) error {
+	return fmt.Errorf("%w (use --allow-lock-mismatch to build anyway):\n  %s", lockfile.ErrMismatch, strings.Join(problems, "\n  "))
+}
+
 var ErrProjectFileNotFound = errors.New("project.godot file not found")
 
+// ErrGodot3VersionUnknown is returned for Godot 3 projects when nothing says
+// which version of Godot to use.
+var ErrGodot3VersionUnknown = errors.New("project.godot was saved by Godot 3, which doesn't record the engine version: use --version, " + lockfile.Name + " or --godot-binary")
+
 func findProjectFile(fs afero.Fs, path string) (string, error) {
 	const filename = "project.godot"
 
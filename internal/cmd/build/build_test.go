@@ -0,0 +1,39 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/lockfile"
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkLockfile(t *testing.T) {
+	t.Setenv(paths.EnvCacheDir, t.TempDir())
+
+	installed, err := cache.LoadOrCreateManifest(paths.NewToolchain("4.3", false))
+	require.NoError(t, err)
+
+	installed.Editor = &cache.Component{Source: "https://example.com/editor.zip", Checksum: "aaa"}
+	installed.Templates = &cache.Component{Source: "https://example.com/templates.tpz", Checksum: "bbb"}
+	require.NoError(t, installed.Write())
+
+	lock := &lockfile.Lockfile{}
+	lock.Update(installed)
+
+	require.NoError(t, checkLockfile(nil, "4.2", false, true, false), "no lockfile")
+	require.NoError(t, checkLockfile(lock, "4.3", false, true, false))
+
+	// Godot 4.3 mono is not in the cache, as with a custom build.
+	require.ErrorIs(t, checkLockfile(lock, "4.3", true, false, false), lockfile.ErrMismatch)
+	require.NoError(t, checkLockfile(lock, "4.3", true, false, true), "--allow-lock-mismatch")
+
+	installed.Templates.Checksum = "ccc"
+	require.NoError(t, installed.Write())
+
+	err = checkLockfile(lock, "4.3", false, true, false)
+	require.ErrorIs(t, err, lockfile.ErrMismatch)
+	assert.Contains(t, err.Error(), "templates checksum is ccc, locked to bbb")
+}
@@ -0,0 +1,169 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/ruffel/godotreleaser/pkg/godot/client"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/exports"
+	"github.com/samber/lo"
+)
+
+// godotBinaryEnv names the environment variable that points at an existing
+// Godot binary to use instead of downloading one.
+const godotBinaryEnv = "GODOT_BIN"
+
+// ErrNoExportTemplates is returned when an existing Godot install has no export
+// templates, and none can be downloaded for it.
+var ErrNoExportTemplates = errors.New("no export templates found")
+
+// systemBinaryNames are looked up on PATH to auto-detect an installed Godot.
+var systemBinaryNames = []string{"godot", "godot4", "godot3"} //nolint:gochecknoglobals
+
+// externalGodot is an existing Godot install used to export the project.
+type externalGodot struct {
+	client *client.Client
+	info   *client.Info
+	path   string
+}
+
+// findExternalGodot looks for an existing Godot install. A binary given via
+// flag or GODOT_BIN is always used, while one found on PATH is only used if it
+// matches the wanted version and flavor. It returns nil if none is found.
+func findExternalGodot(ctx context.Context, binary string, version string, mono bool) (*externalGodot, error) {
+	explicit, source := binary, "--godot-binary"
+	if explicit == "" {
+		explicit, source = os.Getenv(godotBinaryEnv), godotBinaryEnv
+	}
+
+	if explicit != "" {
+		godot, err := probeGodot(ctx, explicit)
+		if err != nil {
+			return nil, fmt.Errorf("godot binary from %s is not usable: %w", source, err)
+		}
+
+		if version != "" && (godot.info.Version != version || godot.info.Mono != mono) {
+			slog.Warn("Godot binary does not match the version wanted by the project",
+				"source", source, "binary", godot.info.Raw, "version", version, "mono", mono)
+		}
+
+		return godot, nil
+	}
+
+	for _, name := range systemBinaryNames {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+
+		godot, err := probeGodot(ctx, path)
+		if err != nil {
+			slog.Debug("Ignoring unusable Godot binary on PATH", "path", path, "error", err)
+
+			continue
+		}
+
+		if godot.info.Version != version || godot.info.Mono != mono {
+			slog.Debug("Ignoring Godot binary on PATH with a different version", "path", path, "binary", godot.info.Raw)
+
+			continue
+		}
+
+		return godot, nil
+	}
+
+	return nil, nil //nolint:nilnil
+}
+
+func probeGodot(ctx context.Context, path string) (*externalGodot, error) {
+	c, err := client.NewFromPath(path)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	info, err := c.Info(ctx)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	// Now that the version is known, use the matching command line.
+	if c, err = client.NewFromPath(path, client.WithVersion(info.Version)); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	slog.Debug("Found Godot binary", "path", path, "version", info.Version, "mono", info.Mono, "raw", info.Raw)
+
+	return &externalGodot{client: c, info: info, path: path}, nil
+}
+
+// newClient returns a client for the external Godot install if there is one,
+// or for the cached toolchain otherwise.
+func newClient(external *externalGodot, version string, mono bool) (*client.Client, error) {
+	if external != nil {
+		return external.client, nil
+	}
+
+	c, err := client.NewFromVersion(version, mono)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if err := cache.Touch(paths.NewToolchain(version, mono)); err != nil {
+		slog.Debug("Failed to record toolchain usage", "version", version, "mono", mono, "error", err)
+	}
+
+	return c, nil
+}
+
+// stable reports whether official export templates can be downloaded for the
+// Godot install. Only stable releases have them.
+func (g *externalGodot) stable() bool {
+	return g.info.Status == "stable"
+}
+
+// checkInstalledTemplates makes sure a Godot install whose export templates
+// can't be downloaded, such as a dev or custom build, has templates to export
+// with: either installed for its version, or set as custom templates in the
+// presets.
+func checkInstalledTemplates(projectDir string, godot *externalGodot) error {
+	info := godot.info
+
+	installed := []string{
+		paths.StatusTemplatePath(info.Version, info.Status, info.Mono),
+		paths.SelfContainedStatusTemplatePath(paths.SelfContainedRoot(godot.path), info.Version, info.Status, info.Mono),
+	}
+
+	for _, dir := range installed {
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+			slog.Info("Using installed export templates", "path", dir)
+
+			return nil
+		}
+	}
+
+	e, err := exports.New(filepath.Join(projectDir, "export_presets.cfg"))
+	if err != nil {
+		return fmt.Errorf("%w for Godot %s in %s: %w", ErrNoExportTemplates, info.Raw, installed[0], err)
+	}
+
+	custom, missing := lo.FilterReject(e.Presets(), func(p exports.Preset, _ int) bool {
+		return p.Options.CustomTemplateRelease != ""
+	})
+
+	if len(custom) == 0 {
+		return fmt.Errorf("%w for Godot %s: install them in %s, or set custom templates in the presets", ErrNoExportTemplates, info.Raw, installed[0])
+	}
+
+	for _, p := range missing {
+		slog.Warn("Preset has no custom export template, and no templates are installed", "preset", p.Name)
+	}
+
+	return nil
+}
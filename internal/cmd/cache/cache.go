@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/pterm/pterm"
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/ruffel/godotreleaser/internal/stages/dependencies"
+	"github.com/ruffel/godotreleaser/internal/utils/httpclient"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var ErrVerificationFailed = errors.New("cache verification failed")
+
+func NewCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage cached Godot binaries and export templates",
+	}
+
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newPruneCmd())
+	cmd.AddCommand(newRemoveCmd())
+	cmd.AddCommand(newVerifyCmd())
+
+	return cmd
+}
+
+func newListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List installed Godot versions",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runList()
+		},
+	}
+}
+
+func runList() error {
+	entries, err := cache.List()
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if len(entries) == 0 {
+		pterm.Info.Println("The cache is empty")
+
+		return nil
+	}
+
+	data := [][]string{{"VERSION", "MONO", "PLATFORM", "SIZE", "LAST USED"}}
+
+	for _, e := range entries {
+		data = append(data, []string{
+			e.Version,
+			lo.Ternary(e.Mono, "yes", "no"),
+			e.Platform.String(),
+			cache.FormatSize(e.Size),
+			e.LastUsed.Local().Format(time.DateTime),
+		})
+	}
+
+	return pterm.DefaultTable.WithHasHeader().WithData(data).Render() //nolint:wrapcheck
+}
+
+type pruneOpts struct {
+	Keep      int
+	OlderThan time.Duration
+	DryRun    bool
+}
+
+func newPruneCmd() *cobra.Command {
+	opts := &pruneOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove least recently used Godot versions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !cmd.Flags().Changed("keep") && !cmd.Flags().Changed("older-than") {
+				return errors.New("at least one of --keep or --older-than is required")
+			}
+
+			return runPrune(opts)
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.Keep, "keep", 0, "Number of most recently used versions to keep")
+	cmd.Flags().DurationVar(&opts.OlderThan, "older-than", 0, "Only remove versions not used within this duration (e.g. 720h)")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Show what would be removed without removing anything")
+
+	return cmd
+}
+
+func runPrune(opts *pruneOpts) error {
+	entries, err := cache.List()
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	prunable := cache.SelectPrunable(entries, opts.Keep, opts.OlderThan, time.Now())
+	if len(prunable) == 0 {
+		pterm.Info.Println("Nothing to prune")
+
+		return nil
+	}
+
+	var freed int64
+
+	for _, e := range prunable {
+		if opts.DryRun {
+			pterm.Info.Printfln("Would remove %s (%s)", e.Name(), cache.FormatSize(e.Size))
+
+			continue
+		}
+
+		if err := cache.Remove(e); err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		freed += e.Size
+
+		pterm.Success.Printfln("Removed %s (%s)", e.Name(), cache.FormatSize(e.Size))
+	}
+
+	if !opts.DryRun {
+		pterm.Info.Printfln("Freed %s", cache.FormatSize(freed))
+	}
+
+	return nil
+}
+
+type removeOpts struct {
+	Mono bool
+	OS   string
+	Arch string
+}
+
+func newRemoveCmd() *cobra.Command {
+	opts := &removeOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "remove <version>",
+		Short: "Remove an installed Godot version",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return runRemove(args[0], opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Mono, "with-mono", false, "Remove the mono version of Godot")
+	cmd.Flags().StringVar(&opts.OS, "os", runtime.GOOS, "Operating system the version was installed for")
+	cmd.Flags().StringVar(&opts.Arch, "arch", runtime.GOARCH, "Architecture the version was installed for")
+
+	return cmd
+}
+
+func runRemove(version string, opts *removeOpts) error {
+	entry, err := cache.Find(paths.Toolchain{Version: version, Mono: opts.Mono, Platform: paths.Platform{OS: opts.OS, Arch: opts.Arch}})
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if err := cache.Remove(entry); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	pterm.Success.Printfln("Removed %s (%s)", entry.Name(), cache.FormatSize(entry.Size))
+
+	return nil
+}
+
+type verifyOpts struct {
+	HTTP httpclient.Config
+}
+
+func newVerifyCmd() *cobra.Command {
+	opts := &verifyOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check installed Godot versions against the published checksums",
+		Long: heredoc.Doc(`
+			Check installed Godot versions against the published checksums.
+
+			The checksum of each downloaded archive is compared with the release's
+			published SHA512-SUMS, and each extracted file with the checksum
+			recorded when it was extracted.
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runVerify(cmd.Context(), opts)
+		},
+	}
+
+	opts.HTTP.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+func runVerify(ctx context.Context, opts *verifyOpts) error {
+	entries, err := cache.List()
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	client, err := opts.HTTP.Client()
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	var failed bool
+
+	for _, e := range entries {
+		var sums map[string]string
+		if e.Manifest != nil {
+			sums = dependencies.FetchChecksums(ctx, client, e.Toolchain())
+		}
+
+		problems, err := cache.Verify(e, sums)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		if len(problems) == 0 {
+			pterm.Success.Printfln("%s: OK", e.Name())
+
+			continue
+		}
+
+		failed = true
+
+		pterm.Error.Printfln("%s: %d problem(s)", e.Name(), len(problems))
+
+		for _, p := range problems {
+			pterm.Printfln("  %s: %s", p.Path, p.Reason)
+		}
+	}
+
+	if failed {
+		return ErrVerificationFailed
+	}
+
+	return nil
+}
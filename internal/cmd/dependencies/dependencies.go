@@ -2,10 +2,15 @@ package dependencies
 
 import (
 	"context"
+	"runtime"
 
+	"github.com/MakeNowJust/heredoc"
+	"github.com/pterm/pterm"
+	"github.com/ruffel/godotreleaser/internal/paths"
 	"github.com/ruffel/godotreleaser/internal/stages/dependencies"
 	"github.com/ruffel/godotreleaser/internal/terminal"
 	"github.com/ruffel/godotreleaser/internal/terminal/messages"
+	"github.com/ruffel/godotreleaser/internal/utils/httpclient"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 )
@@ -13,7 +18,12 @@ import (
 type dependenciesOpts struct {
 	Version string
 	Mono    bool
-	fs      afero.Fs
+	OS      string
+	Arch    string
+	// SelfContained keeps the editor's settings and templates beside it.
+	SelfContained bool
+	HTTP          httpclient.Config
+	fs            afero.Fs
 }
 
 func NewDependenciesCmd() *cobra.Command {
@@ -24,6 +34,13 @@ func NewDependenciesCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "dependencies",
 		Short: "Install Godot dependencies",
+		Long: heredoc.Doc(`
+			Install Godot dependencies.
+
+			When a download fails, what was downloaded is kept and the next run
+			resumes from it. Interrupting with Ctrl-C removes partial downloads
+			and archives that weren't extracted.
+		`),
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			return runDependencies(cmd.Context(), opts)
 		},
@@ -31,6 +48,13 @@ func NewDependenciesCmd() *cobra.Command {
 
 	cmd.Flags().StringVarP(&opts.Version, "version", "v", "4.2.2", "Godot version to use")
 	cmd.Flags().BoolVar(&opts.Mono, "with-mono", false, "Mono version of Godot")
+	cmd.Flags().StringVar(&opts.OS, "os", runtime.GOOS, "Operating system to install Godot for (darwin, linux or windows)")
+	cmd.Flags().StringVar(&opts.Arch, "arch", runtime.GOARCH, "Architecture to install Godot for, using Go's names (amd64, arm64, ...)")
+	cmd.Flags().BoolVar(&opts.SelfContained, "self-contained", false, "Install the editor in self-contained mode, keeping its settings and export templates out of the user profile")
+	opts.HTTP.AddFlags(cmd.Flags())
+
+	cmd.AddCommand(newImportCmd())
+	cmd.AddCommand(newLockCmd())
 
 	return cmd
 }
@@ -38,7 +62,62 @@ func NewDependenciesCmd() *cobra.Command {
 func runDependencies(ctx context.Context, opts *dependenciesOpts) error {
 	terminal.Send(messages.NewSequence("Fetching Godot dependencies"))
 
-	if err := dependencies.Run(ctx, opts.fs, opts.Version, opts.Mono); err != nil {
+	client, err := opts.HTTP.Client()
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	platform := paths.Platform{OS: opts.OS, Arch: opts.Arch}
+
+	deps := &dependencies.Options{Version: opts.Version, Mono: opts.Mono, HTTPClient: client, Platform: platform, SelfContained: opts.SelfContained}
+	if err := dependencies.Run(ctx, opts.fs, deps); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if platform != paths.Host() {
+		t := paths.Toolchain{Version: opts.Version, Mono: opts.Mono, Platform: platform}
+
+		pterm.Info.Printfln("Godot %s for %s is in %s", opts.Version, platform, t.Dir())
+	}
+
+	terminal.Send(messages.NewFooter("Godot dependencies installed"))
+
+	return nil
+}
+
+type importOpts struct {
+	Binary    string
+	Templates string
+}
+
+func newImportCmd() *cobra.Command {
+	opts := &importOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Install Godot dependencies from local archives",
+		Long: heredoc.Doc(`
+			Install the Godot binary and/or export templates from archives on disk,
+			for machines that cannot reach any download mirror.
+
+			The version and mono flavor are detected from the archive contents.
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runImport(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Binary, "binary", "", "Path to a Godot editor archive (.zip)")
+	cmd.Flags().StringVar(&opts.Templates, "templates", "", "Path to a Godot export templates archive (.tpz)")
+
+	return cmd
+}
+
+func runImport(ctx context.Context, opts *importOpts) error {
+	terminal.Send(messages.NewSequence("Importing Godot dependencies"))
+
+	if err := dependencies.Import(ctx, opts.Binary, opts.Templates); err != nil {
 		return err //nolint:wrapcheck
 	}
 
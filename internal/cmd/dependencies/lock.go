@@ -0,0 +1,151 @@
+package dependencies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/pterm/pterm"
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/godot/engine"
+	"github.com/ruffel/godotreleaser/internal/lockfile"
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/ruffel/godotreleaser/internal/stages/dependencies"
+	"github.com/ruffel/godotreleaser/internal/terminal"
+	"github.com/ruffel/godotreleaser/internal/terminal/messages"
+	"github.com/ruffel/godotreleaser/internal/utils/httpclient"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/project"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+type lockOpts struct {
+	ProjectDir string
+	Version    string
+	Mono       bool
+	MonoSet    bool
+	HTTP       httpclient.Config
+	fs         afero.Fs
+}
+
+func newLockCmd() *cobra.Command {
+	opts := &lockOpts{
+		fs: afero.NewOsFs(),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Write or refresh the toolchain lockfile of a project",
+		Long: heredoc.Docf(`
+			Install the Godot toolchain of a project and pin it in %s, next to
+			project.godot. Builds refuse to use a toolchain that doesn't match.
+
+			The version is taken from --version, an existing lockfile or
+			project.godot, in that order.
+		`, lockfile.Name),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			opts.MonoSet = cmd.Flags().Changed("with-mono")
+
+			return runLock(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.ProjectDir, "project", "p", "", "Path to the Godot project directory (defaults to the current directory)")
+	cmd.Flags().StringVarP(&opts.Version, "version", "v", "", "Godot version to lock")
+	cmd.Flags().BoolVar(&opts.Mono, "with-mono", false, "Mono version of Godot")
+	opts.HTTP.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+//nolint:cyclop
+func runLock(ctx context.Context, opts *lockOpts) error {
+	terminal.Send(messages.NewSequence("Locking Godot dependencies"))
+
+	dir := opts.ProjectDir
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to find current directory: %w", err)
+		}
+
+		dir = cwd
+	}
+
+	path := lockfile.Path(dir)
+
+	lock, err := lockfile.Read(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err //nolint:wrapcheck
+		}
+
+		lock = &lockfile.Lockfile{}
+	}
+
+	proj, err := project.New(filepath.Join(dir, "project.godot"))
+	if err != nil {
+		return fmt.Errorf("project file is not valid: %w", err)
+	}
+
+	resolved, ok := engine.ResolveVersion(os.DirFS(dir), proj, opts.Version, lock.Version)
+	if !ok {
+		return errors.New("cannot determine the Godot version, use --version")
+	}
+
+	version := resolved.Version
+	mono := opts.Mono
+
+	if !opts.MonoSet {
+		mono = lock.Mono()
+
+		if lock.Flavor == "" {
+			// Every reason is only looked for when they're logged.
+			detect := project.DetectDotNet
+			if slog.Default().Enabled(ctx, slog.LevelDebug) {
+				detect = project.ExplainDotNet
+			}
+
+			dotnet := detect(os.DirFS(dir), proj)
+			mono = dotnet.Enabled()
+
+			for _, reason := range dotnet.Reasons {
+				slog.Debug("Detected C# project", "reason", reason)
+			}
+		}
+	}
+
+	slog.Debug("Locking toolchain", "version", version, "mono", mono, "path", path)
+
+	client, err := opts.HTTP.Client()
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if err := dependencies.Run(ctx, opts.fs, &dependencies.Options{Version: version, Mono: mono, HTTPClient: client}); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	manifest, err := cache.ReadManifest(paths.NewToolchain(version, mono))
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	lock.Update(manifest)
+
+	if err := lock.Write(path); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	pterm.Success.Printfln("Wrote %s", path)
+
+	terminal.Send(messages.NewFooter("Godot dependencies locked"))
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package paths
+
+import (
+	"github.com/pterm/pterm"
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/spf13/cobra"
+)
+
+func NewPathsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "paths",
+		Short: "Show the directories used for the cache and export templates",
+		Long: "Show the directories used for the cache and export templates, and what they were resolved from.\n\n" +
+			"$" + paths.EnvHome + " moves everything godotreleaser stores, $" + paths.EnvCacheDir + " moves just the cache.\n" +
+			"$XDG_CACHE_HOME is honoured for the cache, and $XDG_DATA_HOME is honoured by Godot on Linux for export templates.",
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runPaths()
+		},
+	}
+}
+
+func runPaths() error {
+	data := [][]string{{"NAME", "PATH", "SOURCE"}}
+
+	for _, l := range paths.Locations() {
+		data = append(data, []string{l.Name, l.Path, l.Source})
+	}
+
+	return pterm.DefaultTable.WithHasHeader().WithData(data).Render() //nolint:wrapcheck
+}
@@ -7,7 +7,10 @@ import (
 
 	charmlog "github.com/charmbracelet/log"
 	"github.com/ruffel/godotreleaser/internal/cmd/build"
+	"github.com/ruffel/godotreleaser/internal/cmd/cache"
 	"github.com/ruffel/godotreleaser/internal/cmd/dependencies"
+	"github.com/ruffel/godotreleaser/internal/cmd/paths"
+	"github.com/ruffel/godotreleaser/internal/cmd/validate"
 	"github.com/ruffel/godotreleaser/internal/cmd/version"
 	"github.com/spf13/cobra"
 )
@@ -29,6 +32,9 @@ func NewRootCmd() *cobra.Command {
 	cmd.AddCommand(build.NewBuildCmd())
 	cmd.AddCommand(version.NewCmdVersion())
 	cmd.AddCommand(dependencies.NewDependenciesCmd())
+	cmd.AddCommand(cache.NewCacheCmd())
+	cmd.AddCommand(paths.NewPathsCmd())
+	cmd.AddCommand(validate.NewValidateCmd())
 
 	return cmd
 }
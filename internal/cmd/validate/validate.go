@@ -0,0 +1,135 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/pterm/pterm"
+	"github.com/ruffel/godotreleaser/internal/godot/engine"
+	"github.com/ruffel/godotreleaser/internal/lockfile"
+	"github.com/ruffel/godotreleaser/internal/stages/validate"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/project"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+type validateOpts struct {
+	ProjectDir string
+	Version    string
+	fs         afero.Fs
+}
+
+func NewValidateCmd() *cobra.Command {
+	opts := &validateOpts{
+		fs: afero.NewOsFs(),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check a Godot project for problems before exporting it",
+		Long: heredoc.Docf(`
+			Check a Godot project for problems that would break an export: missing
+			main scenes, icons, autoloads and other res:// files, a config_version
+			that doesn't match the Godot version, and missing export presets or
+			export paths.
+
+			The Godot version is taken from --version, %s, %s, %s,
+			the Godot.NET.Sdk of a .csproj or project.godot, in that order. Exits
+			with a non-zero status if any errors are found.
+		`, lockfile.Name, project.GodotVersionFile, project.ToolVersionsFile),
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runValidate(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.ProjectDir, "project", "p", "", "Path to the Godot project directory (defaults to the current directory)")
+	cmd.Flags().StringVarP(&opts.Version, "version", "v", "", "Godot version the project will be exported with")
+
+	return cmd
+}
+
+func runValidate(opts *validateOpts) error {
+	dir := opts.ProjectDir
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to find current directory: %w", err)
+		}
+
+		dir = cwd
+	}
+
+	version, err := godotVersion(opts, dir)
+	if err != nil {
+		return err
+	}
+
+	findings, err := validate.Run(opts.fs, dir, validate.Options{Version: version})
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if len(findings) == 0 {
+		pterm.Success.Println("No problems found")
+
+		return nil
+	}
+
+	data := [][]string{{"SEVERITY", "CHECK", "MESSAGE"}}
+
+	for _, f := range findings {
+		data = append(data, []string{string(f.Severity), f.Check, f.Message})
+	}
+
+	if err := pterm.DefaultTable.WithHasHeader().WithData(data).Render(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	count := func(s validate.Severity) int {
+		return lo.CountBy(findings, func(f validate.Finding) bool { return f.Severity == s })
+	}
+
+	errs, warnings := count(validate.Error), count(validate.Warning)
+
+	if errs > 0 {
+		return fmt.Errorf("%w: %d errors, %d warnings", validate.ErrFailed, errs, warnings)
+	}
+
+	pterm.Success.Printfln("No errors found, %d warnings", warnings)
+
+	return nil
+}
+
+// godotVersion returns the version of Godot the project will be exported with,
+// or an empty string if it's unknown.
+func godotVersion(opts *validateOpts, dir string) (string, error) {
+	var locked string
+
+	lock, err := lockfile.ReadFs(opts.fs, lockfile.Path(dir))
+
+	switch {
+	case err == nil:
+		locked = lock.Version
+	case !errors.Is(err, fs.ErrNotExist):
+		return "", err //nolint:wrapcheck
+	}
+
+	// An unreadable or invalid project.godot is reported by the checks.
+	proj := &project.Config{}
+
+	if data, err := afero.ReadFile(opts.fs, filepath.Join(dir, "project.godot")); err == nil {
+		if parsed, err := project.Parse(data); err == nil {
+			proj = parsed
+		}
+	}
+
+	v, _ := engine.ResolveVersion(afero.NewIOFS(afero.NewBasePathFs(opts.fs, dir)), proj, opts.Version, locked)
+
+	return v.Version, nil
+}
@@ -0,0 +1,65 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/ruffel/godotreleaser/internal/stages/validate"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOpts(t *testing.T, files map[string]string) *validateOpts {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, "/game/"+path, []byte(content), 0o644))
+	}
+
+	return &validateOpts{ProjectDir: "/game", fs: fs}
+}
+
+const godot3Project = "config_version=4\n\n[application]\n\nconfig/name=\"Game\"\n"
+
+func Test_godotVersion(t *testing.T) {
+	t.Parallel()
+
+	opts := newOpts(t, map[string]string{
+		"project.godot":      godot3Project,
+		"godotreleaser.lock": `{"version": "3.5.3", "flavor": "standard"}`,
+	})
+
+	version, err := godotVersion(opts, "/game")
+	require.NoError(t, err)
+	assert.Equal(t, "3.5.3", version, "read from the lockfile")
+
+	opts.Version = "3.6"
+
+	version, err = godotVersion(opts, "/game")
+	require.NoError(t, err)
+	assert.Equal(t, "3.6", version, "--version comes first")
+
+	opts = newOpts(t, map[string]string{"project.godot": godot3Project, "godotreleaser.lock": "{"})
+
+	_, err = godotVersion(opts, "/game")
+	assert.Error(t, err, "invalid lockfile")
+}
+
+func Test_runValidate(t *testing.T) {
+	t.Parallel()
+
+	opts := newOpts(t, map[string]string{
+		"project.godot":      godot3Project,
+		"export_presets.cfg": "[preset.0]\n\nname=\"Linux\"\nplatform=\"Linux/X11\"\nexport_path=\"build/game.x86_64\"\n",
+		"godotreleaser.lock": `{"version": "4.3", "flavor": "standard"}`,
+	})
+
+	// The lockfile asks for Godot 4, so config_version=4 is an error.
+	require.ErrorIs(t, runValidate(opts), validate.ErrFailed)
+
+	// Only warnings are left, for the missing main scene and icon.
+	opts.Version = "3.5.3"
+	assert.NoError(t, runValidate(opts))
+}
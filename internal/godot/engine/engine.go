@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Major versions of Godot with different file layouts and command lines.
+const (
+	Godot3 = 3
+	Godot4 = 4
+)
+
+// Major returns the major version of a Godot version such as "3.5.3" or
+// "4.3". Versions that cannot be parsed are assumed to be Godot 4.
+func Major(version string) int {
+	first, _, _ := strings.Cut(version, ".")
+
+	major, err := strconv.Atoi(first)
+	if err != nil || major < Godot3 {
+		return Godot4
+	}
+
+	return major
+}
+
+// IsGodot3 reports whether a version belongs to the Godot 3 series.
+func IsGodot3(version string) bool {
+	return Major(version) == Godot3
+}
@@ -0,0 +1,25 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/ruffel/godotreleaser/internal/godot/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMajor(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]int{
+		"3.5.3": engine.Godot3,
+		"3.6":   engine.Godot3,
+		"4.3":   engine.Godot4,
+		"4.2.2": engine.Godot4,
+		"":      engine.Godot4,
+		"next":  engine.Godot4,
+	}
+
+	for version, want := range tests {
+		assert.Equal(t, want, engine.Major(version), version)
+	}
+}
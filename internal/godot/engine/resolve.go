@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"io/fs"
+	"log/slog"
+
+	"github.com/ruffel/godotreleaser/pkg/godot/config/project"
+)
+
+// ResolveVersion picks the version of Godot to use for a project. A version
+// given on the command line comes first, then the lockfile, then the versions
+// found in the project's files (see project.Config.EngineVersions). Sources
+// that disagree with the chosen version are logged as warnings. It returns
+// false if no version was found.
+func ResolveVersion(fsys fs.FS, config *project.Config, flag, locked string) (project.VersionSource, bool) {
+	var sources []project.VersionSource
+
+	if flag != "" {
+		sources = append(sources, project.VersionSource{Source: "--version", Version: flag, Exact: true})
+	}
+
+	if locked != "" {
+		sources = append(sources, project.VersionSource{Source: "lockfile", Version: locked, Exact: true})
+	}
+
+	sources = append(sources, config.EngineVersions(fsys)...)
+
+	chosen, conflicts, ok := project.ResolveVersion(sources)
+	if !ok {
+		return project.VersionSource{}, false
+	}
+
+	slog.Debug("Using Godot version", "version", chosen.Version, "source", chosen.Source)
+
+	for _, c := range conflicts {
+		slog.Warn("Godot versions disagree", "using", chosen.Version, "from", chosen.Source, "ignored", c.Version, "source", c.Source)
+	}
+
+	return chosen, true
+}
@@ -0,0 +1,94 @@
+package templates
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ruffel/godotreleaser/internal/godot/engine"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/exports"
+	"github.com/samber/lo"
+)
+
+// VersionFile is always part of an export templates install.
+const VersionFile = "version.txt"
+
+const defaultArchitecture = "x86_64"
+
+// platformFiles maps an export platform to the template files it needs, as
+// path.Match patterns. The "{arch}" placeholder is replaced with the
+// architecture configured in the preset.
+var platformFiles = map[string][]string{ //nolint:gochecknoglobals
+	"Linux":           {"linux_debug.{arch}", "linux_release.{arch}"},
+	"Linux/X11":       {"linux_debug.{arch}", "linux_release.{arch}"},
+	"Windows Desktop": {"windows_debug_{arch}*.exe", "windows_release_{arch}*.exe"},
+	"macOS":           {"macos.zip"},
+	"Web":             {"web_*debug.zip", "web_*release.zip"},
+	"Android":         {"android_debug.apk", "android_release.apk", "android_source.zip"},
+	"iOS":             {"ios.zip"},
+}
+
+// platformFiles3 is platformFiles for Godot 3, where "{arch}" is the word size
+// of the preset.
+var platformFiles3 = map[string][]string{ //nolint:gochecknoglobals
+	"Linux/X11":       {"linux_x11_{arch}_debug", "linux_x11_{arch}_release"},
+	"Windows Desktop": {"windows_{arch}_debug.exe", "windows_{arch}_release.exe"},
+	"Mac OSX":         {"osx.zip"},
+	"HTML5":           {"webassembly_*debug.zip", "webassembly_*release.zip"},
+	"Android":         {"android_debug.apk", "android_release.apk", "android_source.zip"},
+	"iOS":             {"iphone.zip"},
+	"UWP":             {"uwp_*_debug.zip", "uwp_*_release.zip"},
+}
+
+// ForPresets returns the template files needed to export the given presets
+// with a version of Godot, as path.Match patterns.
+func ForPresets(presets exports.PresetCollection, version string) ([]string, error) {
+	patterns := []string{VersionFile}
+	godot3 := engine.IsGodot3(version)
+
+	for _, preset := range presets {
+		files, ok := lo.Ternary(godot3, platformFiles3, platformFiles)[preset.Platform]
+		if !ok {
+			return nil, fmt.Errorf("unknown export platform %q in preset %q", preset.Platform, preset.Name)
+		}
+
+		arch := lo.Ternary(preset.Options.BinaryFormatArchitecture != "", preset.Options.BinaryFormatArchitecture, defaultArchitecture)
+		if godot3 {
+			arch = lo.Ternary(preset.Options.BinaryFormat64Bits, "64", "32")
+		}
+
+		for _, f := range files {
+			patterns = append(patterns, replaceArch(f, arch))
+		}
+	}
+
+	patterns = lo.Uniq(patterns)
+	sort.Strings(patterns)
+
+	return patterns, nil
+}
+
+// Matches reports whether name matches any of the patterns.
+func Matches(patterns []string, name string) bool {
+	return lo.ContainsBy(patterns, func(p string) bool {
+		ok, _ := path.Match(p, name)
+
+		return ok
+	})
+}
+
+// Missing returns the patterns not matched by any of the installed files.
+func Missing(patterns []string, installed []string) []string {
+	return lo.Filter(patterns, func(p string, _ int) bool {
+		return !lo.ContainsBy(installed, func(name string) bool {
+			ok, _ := path.Match(p, name)
+
+			return ok
+		})
+	})
+}
+
+func replaceArch(pattern, arch string) string {
+	return strings.ReplaceAll(pattern, "{arch}", arch)
+}
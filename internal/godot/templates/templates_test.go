@@ -0,0 +1,73 @@
+package templates_test
+
+import (
+	"testing"
+
+	"github.com/ruffel/godotreleaser/internal/godot/templates"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/exports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForPresets(t *testing.T) {
+	t.Parallel()
+
+	presets := exports.PresetCollection{
+		{Name: "Windows", Platform: "Windows Desktop", Options: exports.PresetOptions{BinaryFormatArchitecture: "x86_64"}},
+		{Name: "Linux", Platform: "Linux", Options: exports.PresetOptions{BinaryFormatArchitecture: "arm64"}},
+		{Name: "Linux (again)", Platform: "Linux/X11", Options: exports.PresetOptions{BinaryFormatArchitecture: "arm64"}},
+		{Name: "Web", Platform: "Web"},
+	}
+
+	got, err := templates.ForPresets(presets, "4.3")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"linux_debug.arm64",
+		"linux_release.arm64",
+		"version.txt",
+		"web_*debug.zip",
+		"web_*release.zip",
+		"windows_debug_x86_64*.exe",
+		"windows_release_x86_64*.exe",
+	}, got)
+
+	_, err = templates.ForPresets(exports.PresetCollection{{Name: "Console", Platform: "Switch"}}, "4.3")
+	assert.Error(t, err)
+}
+
+func TestForPresets_Godot3(t *testing.T) {
+	t.Parallel()
+
+	presets := exports.PresetCollection{
+		{Name: "Windows", Platform: "Windows Desktop", Options: exports.PresetOptions{BinaryFormat64Bits: true}},
+		{Name: "Linux", Platform: "Linux/X11", Options: exports.PresetOptions{BinaryFormat64Bits: false}},
+		{Name: "macOS", Platform: "Mac OSX"},
+	}
+
+	got, err := templates.ForPresets(presets, "3.5.3")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"linux_x11_32_debug",
+		"linux_x11_32_release",
+		"osx.zip",
+		"version.txt",
+		"windows_64_debug.exe",
+		"windows_64_release.exe",
+	}, got)
+}
+
+func TestMissing(t *testing.T) {
+	t.Parallel()
+
+	patterns := []string{"version.txt", "windows_debug_x86_64*.exe", "windows_release_x86_64*.exe"}
+
+	assert.Empty(t, templates.Missing(patterns, []string{
+		"version.txt", "windows_debug_x86_64.exe", "windows_release_x86_64.exe", "windows_release_x86_64_console.exe",
+	}))
+
+	assert.Equal(t, []string{"windows_release_x86_64*.exe"}, templates.Missing(patterns, []string{
+		"version.txt", "windows_debug_x86_64.exe", "linux_release.x86_64",
+	}))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"runtime"
 
+	"github.com/ruffel/godotreleaser/internal/godot/engine"
 	"github.com/samber/lo"
 )
 
@@ -15,6 +16,7 @@ const (
 	BinaryTemplate     = "Godot_v%s-stable_%s.zip"
 	ExportMonoTemplate = "mono/Godot_v%s-stable_mono_export_templates.tpz"
 	BinaryMonoTemplate = "mono/Godot_v%s-stable_mono_%s.zip"
+	ChecksumsTemplate  = "SHA512-SUMS.txt"
 )
 
 var archMap = map[string]map[string]string{ //nolint:gochecknoglobals
@@ -23,16 +25,35 @@ var archMap = map[string]map[string]string{ //nolint:gochecknoglobals
 	"windows": {"amd64": "win64", "386": "win32"},
 }
 
+// archMap3 holds the platform names used by Godot 3 downloads, which differ
+// between the standard and mono builds on Linux.
+var archMap3 = map[string]map[string]string{ //nolint:gochecknoglobals
+	"linux":      {"amd64": "x11.64", "386": "x11.32"},
+	"linux-mono": {"amd64": "x11_64", "386": "x11_32"},
+}
+
 func selectTemplate(version string, mono bool, goos, arch string) (string, error) {
 	template := lo.Ternary(mono, BinaryMonoTemplate, BinaryTemplate)
 	separator := lo.Ternary(mono, "_", ".")
+	godot3 := engine.IsGodot3(version)
 
 	var osArch string
 
 	switch goos {
 	case "darwin":
-		osArch = "macos.universal"
+		osArch = lo.Ternary(godot3, "osx.universal", "macos.universal")
 	case "linux":
+		if godot3 {
+			linuxArch, ok := archMap3[lo.Ternary(mono, "linux-mono", "linux")][arch]
+			if !ok {
+				return "", fmt.Errorf("unsupported architecture for Godot 3 on Linux: %s", arch)
+			}
+
+			osArch = linuxArch
+
+			break
+		}
+
 		linuxArch, ok := archMap["linux"][arch]
 		if !ok {
 			return "", fmt.Errorf("unsupported architecture for Linux: %s", arch)
@@ -57,11 +78,17 @@ func selectTemplate(version string, mono bool, goos, arch string) (string, error
 }
 
 func BuildBinaryURL(version string, mono bool) (string, error) {
+	return BuildBinaryURLFor(version, mono, runtime.GOOS, runtime.GOARCH)
+}
+
+// BuildBinaryURLFor returns the editor download for another platform, given
+// Go's names for the operating system and architecture.
+func BuildBinaryURLFor(version string, mono bool, goos, arch string) (string, error) {
 	if version == "" {
 		return "", errors.New("version cannot be empty")
 	}
 
-	template, err := selectTemplate(version, mono, runtime.GOOS, runtime.GOARCH)
+	template, err := selectTemplate(version, mono, goos, arch)
 	if err != nil {
 		return "", err
 	}
@@ -78,3 +105,11 @@ func BuildTemplateURL(version string, mono bool) (string, error) {
 
 	return fmt.Sprintf("%s/%s/%s", BaseURL, version, fmt.Sprintf(template, version)), nil
 }
+
+func BuildChecksumsURL(version string) (string, error) {
+	if version == "" {
+		return "", errors.New("version cannot be empty")
+	}
+
+	return fmt.Sprintf("%s/%s/%s", BaseURL, version, ChecksumsTemplate), nil
+}
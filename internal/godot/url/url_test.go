@@ -106,6 +106,46 @@ func Test_selectTemplate(t *testing.T) {
 			arch:    "amd64",
 			want:    "mono/Godot_v4.2.2-stable_mono_win64.zip",
 		},
+		{
+			name:    "godot3-linux-amd64",
+			version: "3.5.3",
+			mono:    false,
+			goos:    "linux",
+			arch:    "amd64",
+			want:    "Godot_v3.5.3-stable_x11.64.zip",
+		},
+		{
+			name:    "godot3-linux-amd64-mono",
+			version: "3.5.3",
+			mono:    true,
+			goos:    "linux",
+			arch:    "amd64",
+			want:    "mono/Godot_v3.5.3-stable_mono_x11_64.zip",
+		},
+		{
+			name:    "godot3-linux-arm64",
+			version: "3.5.3",
+			mono:    false,
+			goos:    "linux",
+			arch:    "arm64",
+			want:    "",
+		},
+		{
+			name:    "godot3-macos",
+			version: "3.5.3",
+			mono:    false,
+			goos:    "darwin",
+			arch:    "arm64",
+			want:    "Godot_v3.5.3-stable_osx.universal.zip",
+		},
+		{
+			name:    "godot3-windows-amd64",
+			version: "3.5.3",
+			mono:    false,
+			goos:    "windows",
+			arch:    "amd64",
+			want:    "Godot_v3.5.3-stable_win64.exe.zip",
+		},
 	}
 
 	for _, tt := range tests {
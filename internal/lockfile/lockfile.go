@@ -0,0 +1,176 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+)
+
+// Name is the filename of the lockfile, stored next to project.godot.
+const Name = "godotreleaser.lock"
+
+const (
+	FlavorStandard = "standard"
+	FlavorMono     = "mono"
+)
+
+// ErrMismatch is returned when the cached toolchain differs from the lockfile.
+var ErrMismatch = errors.New("toolchain does not match lockfile")
+
+// Artifact pins a single downloaded archive.
+type Artifact struct {
+	URL    string `json:"url"`
+	SHA512 string `json:"sha512"`
+}
+
+// Lockfile records the exact toolchain a project is built with.
+//
+// Editor archives differ between hosts, so they are recorded per platform
+// ("linux-amd64", "windows-amd64", ...). Export templates are the same
+// everywhere.
+type Lockfile struct {
+	Version   string               `json:"version"`
+	Flavor    string               `json:"flavor"`
+	Editor    map[string]*Artifact `json:"editor,omitempty"`
+	Templates *Artifact            `json:"templates,omitempty"`
+}
+
+// Path returns the location of the lockfile for a project directory.
+func Path(projectDir string) string {
+	return filepath.Join(projectDir, Name)
+}
+
+// Platform returns the key under which the editor of this host is recorded.
+func Platform() string {
+	return runtime.GOOS + "-" + runtime.GOARCH
+}
+
+// Read loads a lockfile. The returned error wraps fs.ErrNotExist if there is
+// no lockfile.
+func Read(path string) (*Lockfile, error) {
+	return ReadFs(afero.NewOsFs(), path)
+}
+
+// ReadFs is Read for a lockfile in fs.
+func ReadFs(fs afero.Fs, path string) (*Lockfile, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	var l Lockfile
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("failed to decode lockfile %s: %w", path, err)
+	}
+
+	return &l, nil
+}
+
+// Write saves the lockfile to path.
+func (l *Lockfile) Write(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o0644); err != nil { //nolint:gosec
+		return fmt.Errorf("failed to write lockfile %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Mono reports whether the locked toolchain is the mono flavor.
+func (l *Lockfile) Mono() bool {
+	return l.Flavor == FlavorMono
+}
+
+// Update records the toolchain described by a cache manifest, under the
+// platform it was installed for. Editor entries of other platforms are kept,
+// unless the version or flavor changed.
+func (l *Lockfile) Update(m *cache.Manifest) {
+	flavor := lo.Ternary(m.Mono, FlavorMono, FlavorStandard)
+
+	if l.Version != m.Version || l.Flavor != flavor {
+		l.Editor = nil
+	}
+
+	l.Version = m.Version
+	l.Flavor = flavor
+
+	if m.Editor != nil {
+		if l.Editor == nil {
+			l.Editor = make(map[string]*Artifact)
+		}
+
+		l.Editor[m.Toolchain().Platform.String()] = &Artifact{URL: m.Editor.Source, SHA512: m.Editor.Checksum}
+	}
+
+	if m.Templates != nil {
+		l.Templates = &Artifact{URL: m.Templates.Source, SHA512: m.Templates.Checksum}
+	}
+}
+
+// Check compares a cache manifest to the lockfile and returns every
+// difference found. The editor is only checked if checkEditor is set, since an
+// existing Godot install does not come from the cache.
+func (l *Lockfile) Check(m *cache.Manifest, checkEditor bool) []string {
+	problems := l.CheckVersion(m.Version, m.Mono)
+
+	if checkEditor {
+		problems = append(problems, checkArtifact("editor", l.Editor[m.Toolchain().Platform.String()], m.Editor)...)
+	}
+
+	problems = append(problems, checkArtifact("templates", l.Templates, m.Templates)...)
+
+	sort.Strings(problems)
+
+	return problems
+}
+
+// CheckVersion compares a version and flavor to the lockfile, which can be done
+// before the toolchain is installed.
+func (l *Lockfile) CheckVersion(version string, mono bool) []string {
+	var problems []string
+
+	if version != l.Version {
+		problems = append(problems, fmt.Sprintf("version is %s, locked to %s", version, l.Version))
+	}
+
+	if mono != l.Mono() {
+		problems = append(problems, fmt.Sprintf("flavor is %s, locked to %s", lo.Ternary(mono, FlavorMono, FlavorStandard), l.Flavor))
+	}
+
+	return problems
+}
+
+func checkArtifact(name string, locked *Artifact, installed *cache.Component) []string {
+	switch {
+	case locked == nil:
+		return []string{fmt.Sprintf("%s for %s is not locked, run 'dependencies lock' on this platform", name, Platform())}
+	case installed == nil:
+		return []string{name + " is not installed"}
+	case locked.SHA512 != installed.Checksum:
+		return []string{fmt.Sprintf("%s checksum is %s, locked to %s", name, abbreviate(installed.Checksum), abbreviate(locked.SHA512))}
+	}
+
+	return nil
+}
+
+func abbreviate(sum string) string {
+	const length = 16
+
+	if len(sum) <= length {
+		return sum
+	}
+
+	return sum[:length] + "..."
+}
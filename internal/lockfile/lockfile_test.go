@@ -0,0 +1,87 @@
+package lockfile_test
+
+import (
+	"testing"
+
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/lockfile"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func manifest(version string, mono bool, editor, templates string) *cache.Manifest {
+	return &cache.Manifest{
+		Version:   version,
+		Mono:      mono,
+		Editor:    &cache.Component{Source: "https://example.com/editor.zip", Checksum: editor},
+		Templates: &cache.Component{Source: "https://example.com/templates.tpz", Checksum: templates},
+	}
+}
+
+func TestLockfile_Update(t *testing.T) {
+	t.Parallel()
+
+	lock := &lockfile.Lockfile{
+		Version: "4.3",
+		Flavor:  lockfile.FlavorStandard,
+		Editor:  map[string]*lockfile.Artifact{"other-platform": {SHA512: "other"}},
+	}
+
+	lock.Update(manifest("4.3", false, "aaa", "bbb"))
+
+	assert.Equal(t, "other", lock.Editor["other-platform"].SHA512, "other platforms are kept")
+	assert.Equal(t, "aaa", lock.Editor[lockfile.Platform()].SHA512)
+	assert.Equal(t, "bbb", lock.Templates.SHA512)
+
+	lock.Update(manifest("4.3", true, "ccc", "ddd"))
+
+	assert.Equal(t, lockfile.FlavorMono, lock.Flavor)
+	assert.NotContains(t, lock.Editor, "other-platform", "other platforms are dropped when the flavor changes")
+}
+
+func TestLockfile_Check(t *testing.T) {
+	t.Parallel()
+
+	lock := &lockfile.Lockfile{}
+	lock.Update(manifest("4.3", false, "aaa", "bbb"))
+
+	foreign := manifest("4.3", false, "aaa", "bbb")
+	foreign.Platform = lo.Ternary(lockfile.Platform() == "linux-riscv64", "linux-loong64", "linux-riscv64")
+
+	tests := []struct {
+		name        string
+		manifest    *cache.Manifest
+		checkEditor bool
+		want        int
+	}{
+		{name: "match", manifest: manifest("4.3", false, "aaa", "bbb"), checkEditor: true, want: 0},
+		{name: "version", manifest: manifest("4.2.2", false, "aaa", "bbb"), checkEditor: true, want: 1},
+		{name: "flavor", manifest: manifest("4.3", true, "aaa", "bbb"), checkEditor: true, want: 1},
+		{name: "editor", manifest: manifest("4.3", false, "xxx", "bbb"), checkEditor: true, want: 1},
+		{name: "editor not checked", manifest: manifest("4.3", false, "xxx", "bbb"), checkEditor: false, want: 0},
+		{name: "templates", manifest: manifest("4.3", false, "aaa", "xxx"), checkEditor: true, want: 1},
+		{name: "templates missing", manifest: &cache.Manifest{Version: "4.3", Editor: &cache.Component{Checksum: "aaa"}}, checkEditor: true, want: 1},
+		{name: "not locked on that platform", manifest: foreign, checkEditor: true, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Len(t, lock.Check(tt.manifest, tt.checkEditor), tt.want)
+		})
+	}
+}
+
+func TestLockfile_CheckVersion(t *testing.T) {
+	t.Parallel()
+
+	lock := &lockfile.Lockfile{Version: "4.3", Flavor: lockfile.FlavorMono}
+
+	assert.Empty(t, lock.CheckVersion("4.3", true))
+	assert.Equal(t, []string{"version is 4.2.2, locked to 4.3"}, lock.CheckVersion("4.2.2", true))
+	assert.Equal(t, []string{
+		"version is 4.2.2, locked to 4.3",
+		"flavor is standard, locked to mono",
+	}, lock.CheckVersion("4.2.2", false))
+}
@@ -3,20 +3,109 @@ package paths
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
+	"github.com/ruffel/godotreleaser/internal/godot/engine"
 	"github.com/samber/lo"
 )
 
+// Environment variables that move the directories used by godotreleaser.
+const (
+	// EnvHome holds everything godotreleaser stores, with the cache in a
+	// "cache" directory inside it.
+	EnvHome = "GODOTRELEASER_HOME"
+	// EnvCacheDir holds downloaded toolchains, and takes precedence over EnvHome.
+	EnvCacheDir = "GODOTRELEASER_CACHE_DIR"
+)
+
+// Location is a resolved directory and what it was resolved from.
+type Location struct {
+	Name   string
+	Path   string
+	Source string
+}
+
+// Locations returns the directories used by godotreleaser.
+func Locations() []Location {
+	root, rootSource := resolveRoot()
+	cache, cacheSource := resolveCache()
+	data, dataSource := resolveData()
+
+	return []Location{
+		{Name: "home", Path: root, Source: rootSource},
+		{Name: "cache", Path: cache, Source: cacheSource},
+		{Name: "godot data", Path: data, Source: dataSource},
+	}
+}
+
+// Root returns the directory godotreleaser keeps its own files in.
 func Root() string {
+	root, _ := resolveRoot()
+
+	return root
+}
+
+func resolveRoot() (string, string) {
+	if dir := os.Getenv(EnvHome); dir != "" {
+		return dir, "$" + EnvHome
+	}
+
+	return legacyRoot(), "default"
+}
+
+func legacyRoot() string {
 	return filepath.Join(lo.Must(os.UserConfigDir()), ".godotreleaser")
 }
 
+// Cache returns the directory downloaded toolchains are kept in.
 func Cache() string {
-	return filepath.Join(Root(), "cache")
+	cache, _ := resolveCache()
+
+	return cache
+}
+
+// resolveCache picks the cache directory. Toolchains used to be cached inside
+// the config directory, which is still used if it exists and no variable picks
+// another, so that they aren't downloaded again.
+func resolveCache() (string, string) {
+	if dir := os.Getenv(EnvCacheDir); dir != "" {
+		return dir, "$" + EnvCacheDir
+	}
+
+	if dir := os.Getenv(EnvHome); dir != "" {
+		return filepath.Join(dir, "cache"), "$" + EnvHome
+	}
+
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "godotreleaser"), "$XDG_CACHE_HOME"
+	}
+
+	dir := filepath.Join(lo.Must(os.UserCacheDir()), "godotreleaser")
+
+	if legacy := filepath.Join(legacyRoot(), "cache"); dirExists(legacy) {
+		legacyHint.Do(func() {
+			slog.Info("Using the cache in its old location, move it to use the new one", "path", legacy, "new", dir)
+		})
+
+		return legacy, "legacy location, move it to " + dir + " or set $" + EnvCacheDir
+	}
+
+	return dir, "default"
+}
+
+// legacyHint makes sure the hint to move a cache in the old location is only
+// logged once.
+var legacyHint sync.Once //nolint:gochecknoglobals
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+
+	return err == nil && info.IsDir()
 }
 
 func Version(version string, mono bool) string {
@@ -27,82 +116,173 @@ func Version(version string, mono bool) string {
 	return filepath.Join(Cache(), version)
 }
 
-func templateRoot() (string, error) {
-	var dir string
+// Platform is an operating system and architecture, using Go's names for them.
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// Host returns the platform of the running process.
+func Host() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// ParsePlatform parses a platform in the "<os>-<arch>" form, e.g. "linux-arm64".
+func ParsePlatform(s string) (Platform, error) {
+	goos, arch, ok := strings.Cut(s, "-")
+	if !ok || arch == "" || !lo.Contains([]string{"darwin", "linux", "windows"}, goos) {
+		return Platform{}, fmt.Errorf("invalid platform %q, expected <os>-<arch> such as linux-arm64", s)
+	}
+
+	return Platform{OS: goos, Arch: arch}, nil
+}
+
+func (p Platform) String() string {
+	return p.OS + "-" + p.Arch
+}
+
+// Toolchain identifies a Godot install for a target platform.
+type Toolchain struct {
+	Version  string
+	Mono     bool
+	Platform Platform
+}
+
+// NewToolchain returns the toolchain of a version for the host.
+func NewToolchain(version string, mono bool) Toolchain {
+	return Toolchain{Version: version, Mono: mono, Platform: Host()}
+}
+
+// Foreign reports whether the toolchain is for a platform other than the host.
+func (t Toolchain) Foreign() bool {
+	return t.Platform != Host()
+}
+
+// Dir returns the cache directory of the toolchain. Toolchains for the host
+// stay where they always were, those for other platforms are grouped in a
+// directory per platform.
+func (t Toolchain) Dir() string {
+	if !t.Foreign() {
+		return Version(t.Version, t.Mono)
+	}
+
+	return filepath.Join(Cache(), t.Platform.String(), filepath.Base(Version(t.Version, t.Mono)))
+}
+
+// Lock returns the path of the lock guarding installs of the toolchain.
+func (t Toolchain) Lock() string {
+	return t.Dir() + ".lock"
+}
+
+// TemplateDir returns where the export templates of the toolchain go. Godot
+// on this machine has no use for the templates of another platform, so those
+// are kept inside the toolchain directory.
+func (t Toolchain) TemplateDir() string {
+	if !t.Foreign() {
+		return TemplatePath(t.Version, t.Mono)
+	}
+
+	return filepath.Join(t.Dir(), "templates")
+}
+
+// resolveData returns Godot's data directory, which export templates are
+// installed into. Godot decides where it is, so only the variables Godot itself
+// honours can move it.
+func resolveData() (string, string) {
+	dir, err := templateRoot()
+	if err != nil {
+		return "", err.Error()
+	}
+
+	if runtime.GOOS != "windows" && runtime.GOOS != "darwin" && filepath.IsAbs(os.Getenv("XDG_DATA_HOME")) {
+		return dir, "$XDG_DATA_HOME"
+	}
 
+	return dir, "default"
+}
+
+func templateRoot() (string, error) {
 	switch runtime.GOOS {
 	case "windows":
-		dir = os.Getenv("AppData")
+		dir := os.Getenv("AppData")
 		if dir == "" {
 			return "", errors.New("%AppData% is not defined")
 		}
 
-	case "darwin", "ios":
-		dir = os.Getenv("HOME")
-		if dir == "" {
-			return "", errors.New("$HOME is not defined")
+		return dir, nil
+
+	case "darwin":
+		dir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err //nolint:wrapcheck
 		}
 
-		dir += "/Library/Application Support"
+		return filepath.Join(dir, "Library", "Application Support"), nil
 
-	case "plan9":
-		dir = os.Getenv("home")
-		if dir == "" {
-			return "", errors.New("$home is not defined")
+	default: // Unix, where Godot follows the XDG base directory specification.
+		if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+			return dir, nil
 		}
 
-		dir += "/lib"
-
-	default: // Unix
-		dir = os.Getenv("HOME")
-		if dir == "" {
-			return "", errors.New("$HOME is not defined")
+		dir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err //nolint:wrapcheck
 		}
 
-		dir = filepath.Join(dir, ".local", "share")
+		return filepath.Join(dir, ".local", "share"), nil
 	}
-
-	return dir, nil
 }
 
+// TemplatePath returns where Godot looks for the export templates of a
+// version. Godot 3 keeps them in "templates" rather than "export_templates".
 func TemplatePath(version string, mono bool) string {
+	return StatusTemplatePath(version, "stable", mono)
+}
+
+// StatusTemplatePath is TemplatePath for a build with another release status,
+// such as "dev", "beta1" or "custom_build", whose templates can't be downloaded.
+func StatusTemplatePath(version, status string, mono bool) string {
 	root := lo.Must(templateRoot())
 	name := lo.Ternary(runtime.GOOS == "linux", "godot", "Godot")
-	base := fmt.Sprintf("%s.stable%s", version, lo.Ternary(mono, ".mono", ""))
 
-	return filepath.Join(root, name, "export_templates", base)
+	return filepath.Join(root, name, templateSubdir(version, status, mono))
 }
 
-// CheckBinaryExists checks if the Godot binary exists in the specified version directory.
-func CheckBinaryExists(version string, mono bool) (bool, error) {
-	dirPath := Version(version, mono)
-
-	// Check if the directory exists
-	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
-		return false, nil
-	}
-
-	var godotBinaryFound bool
+func templateSubdir(version, status string, mono bool) string {
+	base := fmt.Sprintf("%s.%s%s", version, status, lo.Ternary(mono, ".mono", ""))
+	dir := lo.Ternary(engine.IsGodot3(version), "templates", "export_templates")
 
-	walkFn := func(_ string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	return filepath.Join(dir, base)
+}
 
-		if !info.IsDir() && strings.HasPrefix(info.Name(), "Godot") {
-			godotBinaryFound = true
+// SelfContainedMarker is the file that makes a Godot binary next to it run in
+// self-contained mode, keeping its settings and export templates in an
+// "editor_data" directory beside it instead of the user profile.
+const SelfContainedMarker = "._sc_"
 
-			return filepath.SkipDir
-		}
+// SelfContainedRoot returns the directory the marker and editor data of a
+// self-contained Godot binary go in. On macOS that is next to the app bundle,
+// as the bundle itself may be read-only.
+func SelfContainedRoot(binary string) string {
+	dir := filepath.Dir(binary)
 
-		return nil
+	if filepath.Base(dir) == "MacOS" && filepath.Base(filepath.Dir(dir)) == "Contents" {
+		return filepath.Dir(filepath.Dir(filepath.Dir(dir)))
 	}
 
-	if err := filepath.Walk(dirPath, walkFn); err != nil {
-		return false, err //nolint:wrapcheck
-	}
+	return dir
+}
+
+// SelfContainedTemplatePath returns where a self-contained Godot binary with
+// the given root looks for the export templates of a version.
+func SelfContainedTemplatePath(root, version string, mono bool) string {
+	return SelfContainedStatusTemplatePath(root, version, "stable", mono)
+}
 
-	return godotBinaryFound, nil
+// SelfContainedStatusTemplatePath is SelfContainedTemplatePath for a build
+// with another release status.
+func SelfContainedStatusTemplatePath(root, version, status string, mono bool) string {
+	return filepath.Join(root, "editor_data", templateSubdir(version, status, mono))
 }
 
 // GetBinary retrieves the Godot binary's path for the specified version.
@@ -113,6 +293,11 @@ func GetBinary(version string, mono bool) (string, error) {
 		return "", errors.New("directory does not exist, please download the binary")
 	}
 
+	return FindBinary(dirPath)
+}
+
+// FindBinary returns the first Godot binary found below dir.
+func FindBinary(dir string) (string, error) {
 	var godotBinary string
 
 	walkFn := func(path string, info os.FileInfo, err error) error {
@@ -120,16 +305,22 @@ func GetBinary(version string, mono bool) (string, error) {
 			return err
 		}
 
+		// Neither the C# assemblies nor the data of a self-contained editor
+		// hold the binary, but they have files with similar names.
+		if info.IsDir() && (info.Name() == "GodotSharp" || info.Name() == "editor_data") {
+			return filepath.SkipDir
+		}
+
 		if !info.IsDir() && strings.HasPrefix(info.Name(), "Godot") {
 			godotBinary = path
 
-			return filepath.SkipDir
+			return filepath.SkipAll
 		}
 
 		return nil
 	}
 
-	if err := filepath.Walk(dirPath, walkFn); err != nil {
+	if err := filepath.Walk(dir, walkFn); err != nil {
 		return "", err //nolint:wrapcheck
 	}
 
@@ -0,0 +1,123 @@
+package paths_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlatform(t *testing.T) {
+	t.Parallel()
+
+	p, err := paths.ParsePlatform("linux-arm64")
+	require.NoError(t, err)
+	assert.Equal(t, paths.Platform{OS: "linux", Arch: "arm64"}, p)
+
+	for _, invalid := range []string{"", "linux", "linux-", "4.3-mono", "plan9-amd64"} {
+		_, err := paths.ParsePlatform(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestToolchain_Dir(t *testing.T) {
+	t.Parallel()
+
+	host := paths.NewToolchain("4.3", true)
+	assert.Equal(t, paths.Version("4.3", true), host.Dir())
+	assert.Equal(t, paths.TemplatePath("4.3", true), host.TemplateDir())
+
+	foreign := host
+	foreign.Platform = paths.Platform{OS: "windows", Arch: "386"}
+
+	assert.Equal(t, filepath.Join(paths.Cache(), "windows-386", "4.3-mono"), foreign.Dir())
+	assert.Equal(t, filepath.Join(foreign.Dir(), "templates"), foreign.TemplateDir())
+	assert.Equal(t, foreign.Dir()+".lock", foreign.Lock())
+}
+
+func TestCache(t *testing.T) {
+	home := t.TempDir()
+
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "xdg-cache"))
+	t.Setenv(paths.EnvHome, "")
+	t.Setenv(paths.EnvCacheDir, "")
+
+	assert.Equal(t, filepath.Join(home, "xdg-cache", "godotreleaser"), paths.Cache())
+
+	// An existing cache in the old location keeps being used, unless a
+	// variable picks another.
+	legacy := filepath.Join(home, "config", ".godotreleaser", "cache")
+	require.NoError(t, os.MkdirAll(legacy, 0o755))
+	assert.Equal(t, filepath.Join(home, "xdg-cache", "godotreleaser"), paths.Cache())
+
+	t.Setenv("XDG_CACHE_HOME", "")
+	assert.Equal(t, legacy, paths.Cache())
+
+	t.Setenv(paths.EnvHome, filepath.Join(home, "gr"))
+	assert.Equal(t, filepath.Join(home, "gr"), paths.Root())
+	assert.Equal(t, filepath.Join(home, "gr", "cache"), paths.Cache())
+
+	t.Setenv(paths.EnvCacheDir, filepath.Join(home, "volume"))
+	assert.Equal(t, filepath.Join(home, "volume"), paths.Cache())
+	assert.Equal(t, filepath.Join(home, "volume", "4.3-mono"), paths.Version("4.3", true))
+}
+
+func TestTemplatePath_XDGDataHome(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Godot only honours $XDG_DATA_HOME on Linux and BSD")
+	}
+
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+
+	assert.Equal(t, filepath.Join(data, "godot", "export_templates", "4.3.stable"), paths.TemplatePath("4.3", false))
+	assert.Equal(t, filepath.Join(data, "godot", "templates", "3.6.stable.mono"), paths.TemplatePath("3.6", true))
+	assert.Equal(t, filepath.Join(data, "godot", "export_templates", "4.4.dev"), paths.StatusTemplatePath("4.4", "dev", false))
+
+	t.Setenv("XDG_DATA_HOME", "relative")
+	assert.NotContains(t, paths.TemplatePath("4.3", false), "relative")
+}
+
+func TestSelfContainedRoot(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, filepath.Join("editor", "Godot_v4.3-stable_mono_linux_x86_64"),
+		paths.SelfContainedRoot(filepath.Join("editor", "Godot_v4.3-stable_mono_linux_x86_64", "Godot_v4.3-stable_mono_linux.x86_64")))
+	assert.Equal(t, "editor", paths.SelfContainedRoot(filepath.Join("editor", "Godot.app", "Contents", "MacOS", "Godot")))
+
+	assert.Equal(t, filepath.Join("editor", "editor_data", "export_templates", "4.3.stable.mono"),
+		paths.SelfContainedTemplatePath("editor", "4.3", true))
+	assert.Equal(t, filepath.Join("editor", "editor_data", "templates", "3.6.stable"),
+		paths.SelfContainedTemplatePath("editor", "3.6", false))
+	assert.Equal(t, filepath.Join("editor", "editor_data", "export_templates", "4.3.custom_build"),
+		paths.SelfContainedStatusTemplatePath("editor", "4.3", "custom_build", false))
+}
+
+func TestFindBinary(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "Godot_v4.3-stable_mono_linux_x86_64")
+
+	for _, name := range []string{
+		"GodotSharp/Api/Release/GodotSharp.dll",
+		"Godot_v4.3-stable_mono_linux.x86_64",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, nil, 0o600))
+	}
+
+	binary, err := paths.FindBinary(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "Godot_v4.3-stable_mono_linux.x86_64"), binary)
+
+	_, err = paths.FindBinary(t.TempDir())
+	assert.Error(t, err)
+}
@@ -4,10 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"os"
 	"path/filepath"
 
-	"github.com/ruffel/godotreleaser/internal/paths"
 	"github.com/ruffel/godotreleaser/internal/terminal"
 	"github.com/ruffel/godotreleaser/internal/terminal/messages"
 	"github.com/ruffel/godotreleaser/pkg/godot/client"
@@ -15,21 +13,12 @@ import (
 	"github.com/spf13/afero"
 )
 
-func Run(ctx context.Context, fs afero.Fs, version string, mono bool, path string) error {
-	if err := os.MkdirAll(paths.Version(version, mono), 0o0755); err != nil {
-		return err //nolint:wrapcheck
-	}
-
+func Run(ctx context.Context, fs afero.Fs, c *client.Client, path string) error {
 	e, err := exports.New(filepath.Join(filepath.Dir(path), "export_presets.cfg"))
 	if err != nil {
 		return err //nolint:wrapcheck
 	}
 
-	c, err := client.NewFromVersion(version, mono)
-	if err != nil {
-		return err //nolint:wrapcheck
-	}
-
 	for _, preset := range e.Presets() {
 		name := preset.Name
 		dst := filepath.Join(filepath.Dir(path), filepath.Dir(preset.ExportPath))
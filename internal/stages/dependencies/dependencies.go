@@ -2,26 +2,63 @@ package dependencies
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	iofs "io/fs"
 	"log/slog"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
-	"sync"
 
 	"github.com/pterm/pterm"
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/godot/templates"
 	"github.com/ruffel/godotreleaser/internal/godot/url"
 	"github.com/ruffel/godotreleaser/internal/paths"
 	"github.com/ruffel/godotreleaser/internal/terminal"
 	"github.com/ruffel/godotreleaser/internal/terminal/messages"
+	"github.com/ruffel/godotreleaser/internal/utils/checksum"
 	"github.com/ruffel/godotreleaser/internal/utils/downloader"
-	"github.com/ruffel/godotreleaser/internal/utils/unzip"
 	"github.com/samber/lo"
 	"github.com/spf13/afero"
 )
 
-func Run(_ context.Context, fs afero.Fs, version string, mono bool) error {
-	terminal.Send(messages.NewStage("Configuring Godot " + version))
+// Options configures which Godot toolchain the stage makes sure is installed.
+type Options struct {
+	Version string
+	Mono    bool
+	// TemplatesOnly skips the editor binary, for when the project is exported
+	// with an existing Godot install.
+	TemplatesOnly bool
+	// TemplateFiles limits the export templates to the files matching these
+	// patterns. All templates are installed if it is nil.
+	TemplateFiles []string
+	// SelfContained installs the editor in Godot's self-contained mode, so its
+	// settings and export templates are kept beside it instead of in the user
+	// profile. It has no effect with TemplatesOnly.
+	SelfContained bool
+	// HTTPClient is used for every download. The default client is used if it
+	// is nil.
+	HTTPClient *http.Client
+	// Platform is the platform to install the toolchain for. The zero value
+	// means the host.
+	Platform paths.Platform
+}
+
+// toolchain returns the toolchain selected by the options.
+func (o *Options) toolchain() paths.Toolchain {
+	return paths.Toolchain{
+		Version:  o.Version,
+		Mono:     o.Mono,
+		Platform: lo.Ternary(o.Platform == paths.Platform{}, paths.Host(), o.Platform),
+	}
+}
+
+func Run(ctx context.Context, fs afero.Fs, opts *Options) error {
+	terminal.Send(messages.NewStage("Configuring Godot " + opts.Version))
 
-	if err := downloadGodot(fs, version, mono); err != nil {
+	if err := downloadGodot(ctx, fs, opts); err != nil {
 		return err // nolint:wrapcheck
 	}
 
@@ -29,163 +66,243 @@ func Run(_ context.Context, fs afero.Fs, version string, mono bool) error {
 }
 
 //nolint:cyclop,funlen
-func downloadGodot(fs afero.Fs, version string, mono bool) error {
-	slog.Info("Fetching Godot binaries and export templates", "version", version, "mono", mono)
+func downloadGodot(ctx context.Context, fs afero.Fs, opts *Options) error {
+	toolchain := opts.toolchain()
+	version, mono := toolchain.Version, toolchain.Mono
+
+	slog.Info("Fetching Godot binaries and export templates",
+		"version", version, "mono", mono, "platform", toolchain.Platform, "templatesOnly", opts.TemplatesOnly)
+
+	// Other processes may be installing the same version into the shared cache.
+	// Once we hold the lock, whatever they installed is picked up below.
+	unlock, err := lockToolchain(ctx, toolchain)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	//--------------------------------------------------------------------------
 	// Check if this configuration already exists...
+	//
+	// Only components recorded in the install manifest count as installed, as
+	// anything else may be left over from an interrupted install.
 	//--------------------------------------------------------------------------
-	exportPath := paths.TemplatePath(version, mono)
-
-	binaryExists, err := paths.CheckBinaryExists(version, mono)
+	manifest, err := cache.LoadOrCreateManifest(toolchain)
 	if err != nil {
 		return err //nolint:wrapcheck
 	}
 
-	exportExists, err := afero.Exists(fs, exportPath)
+	selfContained := opts.SelfContained && !opts.TemplatesOnly
+	binaryExists := opts.TemplatesOnly || manifest.Editor.Installed()
+
+	if !opts.TemplatesOnly && binaryExists && manifest.SelfContained != selfContained {
+		if err := markSelfContained(manifest.Editor, selfContained); err != nil {
+			return err
+		}
+
+		manifest.SelfContained = selfContained
+
+		if err := manifest.Write(); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	templatesDst, err := templateDir(toolchain, manifest.Editor, selfContained)
 	if err != nil {
-		return err //nolint:wrapcheck
+		return err
+	}
+
+	// Templates installed for the other mode are where this editor won't look.
+	if manifest.Templates != nil && manifest.Templates.Path != templatesDst {
+		slog.Debug("Export templates are installed elsewhere", "path", manifest.Templates.Path, "wanted", templatesDst)
+
+		manifest.Templates = nil
 	}
 
+	exportExists := manifest.Templates.Installed() && templatesComplete(manifest.Templates, opts.TemplateFiles)
+
 	if binaryExists && exportExists {
 		return nil
 	}
 
-	multi := pterm.DefaultMultiPrinter
+	warnIfRepairing(fs, toolchain, templatesDst, binaryExists, manifest.Templates.Installed())
 
-	binaryTracker := NewDownloadTracker(lo.Must(pterm.DefaultProgressbar.WithWriter(multi.NewWriter()).
-		WithTitle("Downloading Godot Binaries").
-		WithShowCount(false).
-		WithShowPercentage(true).
-		WithShowElapsedTime(true).
-		Start()))
+	versionDir := toolchain.Dir()
 
-	_ = binaryTracker
+	if err := fs.MkdirAll(versionDir, 0o0755); err != nil {
+		return fmt.Errorf("failed to create toolchain directory: %w", err)
+	}
 
-	templateTracker := NewDownloadTracker(lo.Must(pterm.DefaultProgressbar.WithWriter(multi.NewWriter()).
-		WithTitle("Downloading Godot Templates").
-		WithShowCount(false).
-		WithShowPercentage(true).
-		WithShowElapsedTime(true).
-		Start()))
+	//--------------------------------------------------------------------------
+	// Download whatever is missing. The archives are only needed until they are
+	// extracted, so extracted ones are removed on the way out. Archives that
+	// were downloaded but not extracted, because something else failed, are
+	// kept for the next run, as are partial downloads so they can be resumed.
+	// Cancelling, with Ctrl-C, stops for good, so everything is removed then.
+	//--------------------------------------------------------------------------
+	var binaryFetch, templateFetch *fetch
 
-	_ = templateTracker
+	if !binaryExists {
+		address, err := url.BuildBinaryURLFor(version, mono, toolchain.Platform.OS, toolchain.Platform.Arch)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
 
-	if _, err := multi.Start(); err != nil {
-		pterm.Error.Println("Failed to start multi printer:", err)
+		binaryFetch = &fetch{title: "Godot binaries", url: address, dst: filepath.Join(versionDir, "godot.zip")}
+	}
 
-		return err //nolint:wrapcheck
+	if !exportExists {
+		address, err := url.BuildTemplateURL(version, mono)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		templateFetch = &fetch{title: "Godot templates", url: address, dst: filepath.Join(versionDir, "templates.tpz")}
 	}
 
-	var wg sync.WaitGroup
+	fetches := lo.Compact([]*fetch{binaryFetch, templateFetch})
 
-	wg.Add(2) //nolint:mnd
+	defer removeArchives(ctx, fs, fetches)
 
-	binaryZipPath := filepath.Join(paths.Version(version, mono), "godot.zip")
+	client := lo.Ternary(opts.HTTPClient != nil, opts.HTTPClient, http.DefaultClient)
 
-	// Download the files concurrently
-	go func() {
-		defer wg.Done()
+	// The published checksums let corrupt downloads be caught before they are
+	// moved into place.
+	sums := FetchChecksums(ctx, client, toolchain)
 
-		if err := fs.MkdirAll(filepath.Dir(binaryZipPath), 0o0755); err != nil {
-			return
-		}
+	for _, f := range fetches {
+		f.sha512 = sums[path.Base(f.url)]
+	}
 
-		if binaryExists {
-			_, _ = binaryTracker.Tracker.Stop()
+	if err := fetchAll(ctx, client, fetches); err != nil {
+		return err
+	}
 
-			return
-		}
+	//--------------------------------------------------------------------------
+	// Now that we have the files, we can extract them.
+	//--------------------------------------------------------------------------
 
-		binaryAddress, err := url.BuildBinaryURL(version, mono)
-		if err != nil {
-			pterm.Error.Println("Failed to build Godot binary URL:", err)
+	if binaryFetch != nil {
+		// An archive that can't be extracted is likely corrupt, so it isn't
+		// kept either.
+		binaryFetch.extracted = true
 
-			return
+		if manifest.Editor, err = installEditor(ctx, toolchain, binaryFetch.dst, binaryFetch.url, sums); err != nil {
+			return err
 		}
 
-		err = downloader.DownloadFile(context.Background(), binaryAddress, binaryZipPath, downloader.WithProgress(binaryTracker))
-		if err != nil {
-			pterm.Error.Println("Failed to download Godot binary:", err)
+		if selfContained {
+			if err := markSelfContained(manifest.Editor, true); err != nil {
+				return err
+			}
 		}
 
-		slog.Debug("Downloaded godot binaries", "version", version, "mono", mono, "url", binaryAddress, "dst", binaryZipPath)
-	}()
+		manifest.SelfContained = selfContained
 
-	templatePath := filepath.Join(paths.Version(version, mono), "templates.tpz")
-
-	go func() {
-		defer wg.Done()
+		if err := manifest.Write(); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
 
-		if exportExists {
-			_, _ = templateTracker.Tracker.Stop()
+	if templateFetch != nil {
+		templateFetch.extracted = true
 
-			return
+		// A self-contained editor has just been installed if it wasn't already.
+		if templatesDst, err = templateDir(toolchain, manifest.Editor, selfContained); err != nil {
+			return err
 		}
 
-		if err := fs.MkdirAll(filepath.Dir(templatePath), 0o0755); err != nil {
-			return
+		if manifest.Templates, err = installTemplates(ctx, templatesDst, templateFetch.dst, templateFetch.url, sums, opts.TemplateFiles, manifest.Templates); err != nil {
+			return err
 		}
 
-		templateAddress, _ := url.BuildTemplateURL(version, mono)
-
-		err := downloader.DownloadFile(context.Background(), templateAddress, templatePath, downloader.WithProgress(templateTracker))
-		if err != nil {
-			pterm.Error.Println("Failed to download Godot templates:", err)
+		if err := manifest.Write(); err != nil {
+			return err //nolint:wrapcheck
 		}
+	}
 
-		slog.Debug("Downloaded godot export templates", "version", version, "mono", mono, "url", templateAddress, "dst", templatePath)
-	}()
-
-	wg.Wait()
+	pterm.Success.Println("Godot and templates extracted successfully")
 
-	_, _ = multi.Stop()
+	return nil
+}
 
-	versionDir := paths.Version(version, mono)
+// templatesComplete reports whether the installed export templates cover what
+// is wanted: either all of them, or the files matching the wanted patterns.
+func templatesComplete(c *cache.Component, wanted []string) bool {
+	if wanted == nil {
+		return !c.Partial
+	}
 
-	if !binaryExists {
-		// Now that we have the files, we can extract them.
-		pterm.Info.Println("Extracting Godot binary...")
+	return len(templates.Missing(wanted, c.FileNames())) == 0
+}
 
-		src := binaryZipPath
-		dst := filepath.Join(versionDir, "editor")
+// warnIfRepairing reports components that exist on disk but were never
+// completely installed, and are about to be replaced.
+func warnIfRepairing(fs afero.Fs, t paths.Toolchain, templatesDst string, binaryExists, exportExists bool) {
+	editorDir := filepath.Join(t.Dir(), "editor")
 
-		if err := unzip.Extract(src, dst); err != nil {
-			pterm.Error.Println("Failed to extract Godot binary:", err)
+	if found, _ := afero.DirExists(fs, editorDir); found && !binaryExists {
+		pterm.Warning.Printfln("Found an incomplete Godot %s install, repairing it", t.Version)
+	}
 
-			return err //nolint:wrapcheck
-		}
+	if templatesDst == "" {
+		return
+	}
 
-		slog.Debug("Extracted godot binaries", "src", src, "dst", dst)
+	if found, _ := afero.DirExists(fs, templatesDst); found && !exportExists {
+		pterm.Warning.Printfln("Found incomplete Godot %s export templates, repairing them", t.Version)
 	}
+}
 
-	if !exportExists {
-		pterm.Info.Println("Extracting Godot templates...")
+// removeArchives removes the archives that were extracted, or, once ctx is
+// cancelled, every archive and partial download.
+func removeArchives(ctx context.Context, fs afero.Fs, fetches []*fetch) {
+	cancelled := ctx.Err() != nil
 
-		src := templatePath
-		dst := paths.TemplatePath(version, mono)
+	for _, f := range fetches {
+		if !f.extracted && !cancelled {
+			continue
+		}
 
-		if err := unzip.Extract(src, dst); err != nil {
-			pterm.Error.Println("Failed to extract Godot templates:", err)
+		if err := fs.Remove(f.dst); err != nil && !errors.Is(err, iofs.ErrNotExist) {
+			pterm.Warning.Printfln("Failed to remove %s: %v", f.dst, err)
+		}
 
-			return err //nolint:wrapcheck
+		if !cancelled {
+			continue
 		}
 
-		slog.Debug("Extracted Godot export templates", "src", src, "dst", dst)
+		if err := downloader.RemovePartial(f.dst); err != nil {
+			pterm.Warning.Printfln("Failed to remove partial download of %s: %v", f.dst, err)
+		}
 	}
+}
 
-	// Clean up zip files
-	if err := os.Remove(binaryZipPath); err != nil {
-		pterm.Warning.Printf("Failed to remove binary zip file: %v\n", err)
+// FetchChecksums downloads the published SHA-512 checksums for a release. The
+// checksums are optional, so failures are logged rather than returned.
+func FetchChecksums(ctx context.Context, client *http.Client, t paths.Toolchain) map[string]string {
+	address, err := url.BuildChecksumsURL(t.Version)
+	if err != nil {
+		return nil
 	}
 
-	if err := os.Remove(templatePath); err != nil {
-		pterm.Warning.Printf("Failed to remove template zip file: %v\n", err)
+	dst := filepath.Join(t.Dir(), url.ChecksumsTemplate)
+	defer os.Remove(dst)
+
+	if err := downloader.DownloadFile(ctx, address, dst, downloader.WithHTTPClient(client), downloader.WithResume(false), downloader.WithRetries(1)); err != nil {
+		slog.Warn("Published checksums are not available, archives cannot be verified", "url", address, "error", err)
+
+		return nil
 	}
 
-	pterm.Success.Println("Godot and templates extracted successfully")
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		slog.Warn("Failed to read published checksums", "path", dst, "error", err)
 
-	return nil
+		return nil
+	}
+
+	return checksum.ParseSums(data)
 }
 
 type DownloadTracker struct {
@@ -195,6 +312,11 @@ type DownloadTracker struct {
 }
 
 func (d *DownloadTracker) Update(downloaded int64, total int64) {
+	// Without a content length the bar cannot show anything meaningful.
+	if total < 0 {
+		return
+	}
+
 	if d.total != total {
 		d.total = total
 		d.Tracker.Total = int(d.total)
@@ -0,0 +1,170 @@
+package dependencies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/pterm/pterm"
+	"github.com/ruffel/godotreleaser/internal/terminal"
+	"github.com/ruffel/godotreleaser/internal/utils/checksum"
+	"github.com/ruffel/godotreleaser/internal/utils/downloader"
+	"github.com/samber/lo"
+)
+
+// connections is the number of ranges each archive is split into, to make up
+// for slow single connections to the mirrors.
+const connections = 4
+
+// fetch is a single archive to download.
+type fetch struct {
+	title string
+	url   string
+	dst   string
+	// sha512 is the published digest of the archive, if known.
+	sha512 string
+	// extracted is set once the archive has been used, after which it is no
+	// longer needed.
+	extracted bool
+}
+
+// downloaded reports whether the archive was completely downloaded by an
+// earlier run that failed before extracting it. An archive that doesn't match
+// the published digest is removed so it can be downloaded again.
+func (f *fetch) downloaded() bool {
+	if _, err := os.Stat(f.dst); err != nil {
+		return false
+	}
+
+	if f.sha512 == "" {
+		return true
+	}
+
+	if sum, err := checksum.SHA512File(f.dst); err == nil && sum == f.sha512 {
+		return true
+	}
+
+	slog.Debug("Discarding archive that doesn't match its checksum", "path", f.dst)
+
+	_ = os.Remove(f.dst)
+
+	return false
+}
+
+// fetchAll downloads the archives concurrently, skipping those that are
+// already downloaded. If any download fails, the others are cancelled and the
+// errors of all failed downloads are returned.
+func fetchAll(ctx context.Context, client *http.Client, fetches []*fetch) error {
+	fetches = lo.Reject(fetches, func(f *fetch, _ int) bool {
+		if !f.downloaded() {
+			return false
+		}
+
+		slog.Debug("Reusing downloaded "+f.title, "path", f.dst)
+
+		return true
+	})
+
+	if len(fetches) == 0 {
+		return nil
+	}
+
+	fetchCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	trackers, stop, err := newTrackers(fetches)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for i, f := range fetches {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			opts := []downloader.Option{
+				downloader.WithHTTPClient(client),
+				downloader.WithProgress(trackers[i]),
+				downloader.WithConnections(connections),
+			}
+
+			if f.sha512 != "" {
+				opts = append(opts, downloader.WithSHA512(f.sha512))
+			}
+
+			err := downloader.DownloadFile(fetchCtx, f.url, f.dst, opts...)
+			if err == nil {
+				slog.Debug("Downloaded "+f.title, "url", f.url, "dst", f.dst)
+
+				return
+			}
+
+			// Cancelled either by the caller, or because another download
+			// failed. Both are reported below rather than once per download.
+			if errors.Is(err, context.Canceled) && fetchCtx.Err() != nil {
+				return
+			}
+
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("failed to download %s: %w", f.title, err))
+			mu.Unlock()
+
+			cancel(err)
+		}()
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return errors.Join(errs...)
+}
+
+// newTrackers returns a progress tracker per fetch: progress bars on a
+// terminal, or periodic progress lines otherwise.
+func newTrackers(fetches []*fetch) ([]downloader.ProgressTracker, func(), error) {
+	if !terminal.Interactive() {
+		return lo.Map(fetches, func(f *fetch, _ int) downloader.ProgressTracker {
+			return downloader.NewSimpleTracker(os.Stdout, "Downloading "+f.title)
+		}), func() {}, nil
+	}
+
+	multi := pterm.DefaultMultiPrinter
+
+	bars := lo.Map(fetches, func(f *fetch, _ int) *DownloadTracker {
+		return NewDownloadTracker(lo.Must(pterm.DefaultProgressbar.WithWriter(multi.NewWriter()).
+			WithTitle("Downloading " + f.title).
+			WithShowCount(false).
+			WithShowPercentage(true).
+			WithShowElapsedTime(true).
+			Start()))
+	})
+
+	if _, err := multi.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start progress output: %w", err)
+	}
+
+	stop := func() {
+		for _, b := range bars {
+			_, _ = b.Tracker.Stop()
+		}
+
+		_, _ = multi.Stop()
+	}
+
+	return lo.Map(bars, func(b *DownloadTracker, _ int) downloader.ProgressTracker { return b }), stop, nil
+}
@@ -0,0 +1,161 @@
+package dependencies
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fetchAll_FailureCancelsOthers(t *testing.T) {
+	t.Parallel()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.WriteHeader(http.StatusOK)
+
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	}))
+	t.Cleanup(slow.Close)
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(missing.Close)
+
+	dir := t.TempDir()
+
+	fetches := []*fetch{
+		{title: "slow", url: slow.URL, dst: filepath.Join(dir, "slow.zip")},
+		{title: "missing", url: missing.URL, dst: filepath.Join(dir, "missing.zip")},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := fetchAll(ctx, http.DefaultClient, fetches)
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "failed to download missing")
+	assert.NotContains(t, err.Error(), "failed to download slow")
+	assert.NoError(t, ctx.Err(), "the slow download should have been cancelled")
+}
+
+func Test_fetchAll_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.WriteHeader(http.StatusOK)
+
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	}))
+	t.Cleanup(slow.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	err := fetchAll(ctx, http.DefaultClient, []*fetch{{title: "slow", url: slow.URL, dst: filepath.Join(t.TempDir(), "slow.zip")}})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_fetchAll_ReusesDownloaded(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("complete archive")
+	sum := sha512.Sum512(content)
+
+	var downloads atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			downloads.Add(1)
+		}
+
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "kept.zip")
+	corrupt := filepath.Join(dir, "corrupt.zip")
+
+	require.NoError(t, os.WriteFile(kept, content, 0o600))
+	require.NoError(t, os.WriteFile(corrupt, []byte("truncated"), 0o600))
+
+	err := fetchAll(context.Background(), http.DefaultClient, []*fetch{
+		{title: "kept", url: server.URL, dst: kept, sha512: hex.EncodeToString(sum[:])},
+		{title: "corrupt", url: server.URL, dst: corrupt, sha512: hex.EncodeToString(sum[:])},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), downloads.Load(), "only the corrupt archive should be downloaded again")
+
+	data, err := os.ReadFile(corrupt)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+}
+
+func Test_removeArchives(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T) []*fetch {
+		t.Helper()
+
+		dir := t.TempDir()
+		extracted := &fetch{dst: filepath.Join(dir, "godot.zip"), extracted: true}
+		kept := &fetch{dst: filepath.Join(dir, "templates.tpz")}
+
+		for _, path := range []string{extracted.dst, kept.dst, kept.dst + ".part", kept.dst + ".part.json"} {
+			require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+		}
+
+		return []*fetch{extracted, kept}
+	}
+
+	t.Run("failed", func(t *testing.T) {
+		t.Parallel()
+
+		fetches := setup(t)
+		removeArchives(context.Background(), afero.NewOsFs(), fetches)
+
+		assert.NoFileExists(t, fetches[0].dst)
+		assert.FileExists(t, fetches[1].dst)
+		assert.FileExists(t, fetches[1].dst+".part")
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		fetches := setup(t)
+		removeArchives(ctx, afero.NewOsFs(), fetches)
+
+		assert.NoFileExists(t, fetches[0].dst)
+		assert.NoFileExists(t, fetches[1].dst)
+		assert.NoFileExists(t, fetches[1].dst+".part")
+		assert.NoFileExists(t, fetches[1].dst+".part.json")
+	})
+}
@@ -0,0 +1,188 @@
+package dependencies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/ruffel/godotreleaser/internal/terminal"
+	"github.com/ruffel/godotreleaser/internal/terminal/messages"
+	"github.com/ruffel/godotreleaser/internal/utils/unzip"
+	"github.com/samber/lo"
+)
+
+const templateVersionFile = "version.txt"
+
+var binaryNamePattern = regexp.MustCompile(`Godot_v([0-9][0-9.]*)-stable_(mono_)?`)
+
+// ErrNoArchives is returned when an import is requested without any archives.
+var ErrNoArchives = errors.New("at least one of the binary or templates archive is required")
+
+// Import installs the Godot binary and/or export templates from local archives,
+// exactly as if they had been downloaded. The version and flavor are detected
+// from the archive contents.
+func Import(ctx context.Context, binary, templates string) error {
+	if binary == "" && templates == "" {
+		return ErrNoArchives
+	}
+
+	version, mono, err := detectImportVersion(binary, templates)
+	if err != nil {
+		return err
+	}
+
+	terminal.Send(messages.NewStage(fmt.Sprintf("Importing Godot %s%s", version, lo.Ternary(mono, " (mono)", ""))))
+
+	slog.Info("Importing Godot from local archives", "version", version, "mono", mono, "binary", binary, "templates", templates)
+
+	// Archives on disk are assumed to be for this machine.
+	toolchain := paths.NewToolchain(version, mono)
+
+	unlock, err := lockToolchain(ctx, toolchain)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	manifest, err := cache.LoadOrCreateManifest(toolchain)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if binary != "" {
+		if manifest.Editor, err = importComponent(binary, func(archive, source string) (*cache.Component, error) {
+			return installEditor(ctx, toolchain, archive, source, nil)
+		}); err != nil {
+			return err
+		}
+
+		// The toolchain keeps the mode it was installed in.
+		if manifest.SelfContained {
+			if err := markSelfContained(manifest.Editor, true); err != nil {
+				return err
+			}
+		}
+
+		if err := manifest.Write(); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	if templates != "" {
+		dst, err := templateDir(toolchain, manifest.Editor, manifest.SelfContained)
+		if err != nil {
+			return err
+		}
+
+		if dst == "" {
+			return fmt.Errorf("godot %s is self-contained but its editor is missing, import the binary too", version)
+		}
+
+		if manifest.Templates, err = importComponent(templates, func(archive, source string) (*cache.Component, error) {
+			return installTemplates(ctx, dst, archive, source, nil, nil, nil)
+		}); err != nil {
+			return err
+		}
+
+		if err := manifest.Write(); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	pterm.Success.Printfln("Imported Godot %s", version)
+
+	return nil
+}
+
+// importComponent installs a local archive and marks the result as verified,
+// since the archive was explicitly provided by the user.
+func importComponent(archive string, install func(archive, source string) (*cache.Component, error)) (*cache.Component, error) {
+	abs, err := filepath.Abs(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", archive, err)
+	}
+
+	c, err := install(abs, "file://"+filepath.ToSlash(abs))
+	if err != nil {
+		return nil, err
+	}
+
+	c.Verified = true
+
+	return c, nil
+}
+
+// detectImportVersion works out the version and flavor from the archives,
+// making sure that both archives agree with each other.
+func detectImportVersion(binary, templates string) (string, bool, error) {
+	var (
+		binaryVersion, templateVersion string
+		binaryMono, templateMono       bool
+		err                            error
+	)
+
+	if templates != "" {
+		data, err := unzip.ReadFile(templates, templateVersionFile)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read templates version: %w", err)
+		}
+
+		if templateVersion, templateMono, err = parseTemplateVersion(string(data)); err != nil {
+			return "", false, err
+		}
+
+		if binary == "" {
+			return templateVersion, templateMono, nil
+		}
+	}
+
+	names, err := unzip.Names(binary)
+	if err != nil {
+		return "", false, err //nolint:wrapcheck
+	}
+
+	if binaryVersion, binaryMono, err = parseBinaryVersion(names); err != nil {
+		return "", false, err
+	}
+
+	if templates != "" && (binaryVersion != templateVersion || binaryMono != templateMono) {
+		return "", false, fmt.Errorf("binary (%s, mono=%t) and templates (%s, mono=%t) do not match",
+			binaryVersion, binaryMono, templateVersion, templateMono)
+	}
+
+	return binaryVersion, binaryMono, nil
+}
+
+// parseTemplateVersion parses the contents of the templates version file, for
+// example "4.3.stable" or "4.2.2.stable.mono".
+func parseTemplateVersion(data string) (string, bool, error) {
+	raw := strings.TrimSpace(data)
+
+	rest, mono := strings.CutSuffix(raw, ".mono")
+
+	version, ok := strings.CutSuffix(rest, ".stable")
+	if !ok || version == "" {
+		return "", false, fmt.Errorf("unsupported templates version %q, only stable releases are supported", raw)
+	}
+
+	return version, mono, nil
+}
+
+// parseBinaryVersion finds the version and flavor in the entry names of an
+// editor archive, which follow the naming of the official downloads.
+func parseBinaryVersion(names []string) (string, bool, error) {
+	for _, name := range names {
+		if m := binaryNamePattern.FindStringSubmatch(name); m != nil {
+			return strings.TrimSuffix(m[1], "."), m[2] != "", nil
+		}
+	}
+
+	return "", false, errors.New("archive does not look like an official Godot editor download")
+}
@@ -0,0 +1,81 @@
+package dependencies
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseTemplateVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    string
+		version  string
+		mono     bool
+		hasError bool
+	}{
+		{input: "4.3.stable\n", version: "4.3"},
+		{input: "4.2.2.stable.mono", version: "4.2.2", mono: true},
+		{input: "3.5.3.stable.mono\r\n", version: "3.5.3", mono: true},
+		{input: "4.4.beta1", hasError: true},
+		{input: "", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+
+			version, mono, err := parseTemplateVersion(tt.input)
+			if tt.hasError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.version, version)
+			assert.Equal(t, tt.mono, mono)
+		})
+	}
+}
+
+func Test_parseBinaryVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		names    []string
+		version  string
+		mono     bool
+		hasError bool
+	}{
+		{name: "linux", names: []string{"Godot_v4.3-stable_linux.x86_64"}, version: "4.3"},
+		{name: "windows", names: []string{"Godot_v4.2.2-stable_win64.exe", "Godot_v4.2.2-stable_win64_console.exe"}, version: "4.2.2"},
+		{
+			name:    "mono",
+			names:   []string{"Godot_v4.3-stable_mono_linux_x86_64/", "Godot_v4.3-stable_mono_linux_x86_64/Godot_v4.3-stable_mono_linux.x86_64"},
+			version: "4.3",
+			mono:    true,
+		},
+		{name: "godot 3", names: []string{"Godot_v3.5.3-stable_x11.64"}, version: "3.5.3"},
+		{name: "unknown", names: []string{"README.md"}, hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			version, mono, err := parseBinaryVersion(tt.names)
+			if tt.hasError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.version, version)
+			assert.Equal(t, tt.mono, mono)
+		})
+	}
+}
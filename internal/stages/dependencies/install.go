@@ -0,0 +1,170 @@
+package dependencies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/godot/templates"
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/ruffel/godotreleaser/internal/utils/checksum"
+	"github.com/ruffel/godotreleaser/internal/utils/filelock"
+	"github.com/ruffel/godotreleaser/internal/utils/unzip"
+)
+
+// lockToolchain takes the cross-process install lock of a toolchain, waiting
+// for any other holder to finish. The returned function releases the lock.
+func lockToolchain(ctx context.Context, t paths.Toolchain) (func(), error) {
+	lock, err := filelock.Acquire(ctx, t.Lock(), filelock.WithOnWait(func(holder string) {
+		pterm.Info.Printfln("Waiting for another process to finish installing Godot %s (%s)", t.Version, holder)
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock Godot %s: %w", t.Version, err)
+	}
+
+	return func() {
+		if err := lock.Release(); err != nil {
+			slog.Warn("Failed to release install lock", "version", t.Version, "mono", t.Mono, "platform", t.Platform, "error", err)
+		}
+	}, nil
+}
+
+// installEditor extracts an editor archive into the toolchain directory,
+// replacing any incomplete install.
+func installEditor(ctx context.Context, t paths.Toolchain, archive, source string, sums map[string]string) (*cache.Component, error) {
+	pterm.Info.Println("Extracting Godot binary...")
+
+	dst := filepath.Join(t.Dir(), "editor")
+
+	if err := unzip.ExtractAtomic(ctx, archive, dst, nil); err != nil {
+		pterm.Error.Println("Failed to extract Godot binary:", err)
+
+		return nil, err //nolint:wrapcheck
+	}
+
+	slog.Debug("Extracted godot binaries", "src", archive, "dst", dst)
+
+	return newComponent(archive, dst, source, sums)
+}
+
+// templateDir returns where the export templates of a toolchain belong. Those
+// of a self-contained editor are beside its binary, so the location is only
+// known once the editor is installed, and is empty until then.
+func templateDir(t paths.Toolchain, editor *cache.Component, selfContained bool) (string, error) {
+	if !selfContained {
+		return t.TemplateDir(), nil
+	}
+
+	if !editor.Installed() {
+		return "", nil
+	}
+
+	binary, err := paths.FindBinary(editor.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to find Godot %s binary: %w", t.Version, err)
+	}
+
+	return paths.SelfContainedTemplatePath(paths.SelfContainedRoot(binary), t.Version, t.Mono), nil
+}
+
+// markSelfContained adds or removes the marker that makes an installed editor
+// run in self-contained mode.
+func markSelfContained(editor *cache.Component, enabled bool) error {
+	binary, err := paths.FindBinary(editor.Path)
+	if err != nil {
+		return fmt.Errorf("failed to find Godot binary: %w", err)
+	}
+
+	marker := filepath.Join(paths.SelfContainedRoot(binary), paths.SelfContainedMarker)
+
+	if !enabled {
+		if err := os.Remove(marker); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove self-contained marker: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := os.WriteFile(marker, nil, 0o0644); err != nil { //nolint:gosec
+		return fmt.Errorf("failed to write self-contained marker: %w", err)
+	}
+
+	slog.Debug("Installed editor in self-contained mode", "marker", marker)
+
+	return nil
+}
+
+// installTemplates extracts an export templates archive into dst, replacing
+// any incomplete install.
+//
+// If wanted is set, only the template files matching it are extracted. Files
+// of an existing partial install are kept, so templates for more platforms
+// can be added over time.
+//
+//nolint:cyclop
+func installTemplates(
+	ctx context.Context, dst, archive, source string, sums map[string]string, wanted []string, existing *cache.Component,
+) (*cache.Component, error) {
+	pterm.Info.Println("Extracting Godot templates...")
+
+	var err error
+
+	switch {
+	case wanted == nil:
+		err = unzip.ExtractAtomic(ctx, archive, dst, nil)
+	case existing.Installed():
+		missing := templates.Missing(wanted, existing.FileNames())
+		slog.Debug("Adding missing export templates", "files", missing)
+
+		err = unzip.ExtractMerge(ctx, archive, dst, func(name string) bool { return templates.Matches(missing, name) })
+	default:
+		err = unzip.ExtractAtomic(ctx, archive, dst, func(name string) bool { return templates.Matches(wanted, name) })
+	}
+
+	if err != nil {
+		pterm.Error.Println("Failed to extract Godot templates:", err)
+
+		return nil, err //nolint:wrapcheck
+	}
+
+	slog.Debug("Extracted Godot export templates", "src", archive, "dst", dst)
+
+	c, err := newComponent(archive, dst, source, sums)
+	if err != nil {
+		return nil, err
+	}
+
+	if wanted != nil {
+		if missing := templates.Missing(wanted, c.FileNames()); len(missing) > 0 {
+			return nil, fmt.Errorf("export templates archive has no files matching %s", strings.Join(missing, ", "))
+		}
+
+		c.Partial = true
+	}
+
+	return c, nil
+}
+
+// newComponent checks an archive against the published checksums and records
+// the files it was extracted to.
+func newComponent(archive, dst, source string, sums map[string]string) (*cache.Component, error) {
+	sum, err := checksum.SHA512File(archive)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	want, published := sums[path.Base(source)]
+	if published && want != sum {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path.Base(source), want, sum)
+	}
+
+	return cache.NewComponent(dst, source, sum, published) //nolint:wrapcheck
+}
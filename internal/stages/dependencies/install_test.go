@@ -0,0 +1,48 @@
+package dependencies
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ruffel/godotreleaser/internal/cache"
+	"github.com/ruffel/godotreleaser/internal/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_markSelfContained(t *testing.T) {
+	t.Parallel()
+
+	editor := &cache.Component{Path: t.TempDir()}
+	binary := filepath.Join(editor.Path, "Godot_v4.3-stable_linux.x86_64")
+	require.NoError(t, os.WriteFile(binary, nil, 0o600))
+
+	toolchain := paths.NewToolchain("4.3", false)
+
+	dir, err := templateDir(toolchain, editor, false)
+	require.NoError(t, err)
+	assert.Equal(t, toolchain.TemplateDir(), dir)
+
+	dir, err = templateDir(toolchain, editor, true)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(editor.Path, "editor_data", "export_templates", "4.3.stable"), dir)
+
+	marker := filepath.Join(editor.Path, paths.SelfContainedMarker)
+
+	require.NoError(t, markSelfContained(editor, true))
+	assert.FileExists(t, marker)
+
+	require.NoError(t, markSelfContained(editor, false))
+	assert.NoFileExists(t, marker)
+
+	require.NoError(t, markSelfContained(editor, false), "removing a missing marker")
+}
+
+func Test_templateDir_EditorMissing(t *testing.T) {
+	t.Parallel()
+
+	dir, err := templateDir(paths.NewToolchain("4.3", false), nil, true)
+	require.NoError(t, err)
+	assert.Empty(t, dir)
+}
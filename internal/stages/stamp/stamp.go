@@ -0,0 +1,227 @@
+// Package stamp writes the release version into a project for the duration of
+// a build, so that ProjectSettings.get_setting("application/config/version")
+// matches the release being exported.
+package stamp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/document"
+	"github.com/spf13/afero"
+)
+
+// BackupSuffix is added to the name of a file to name its backup. A backup
+// left behind by a build that was killed is restored by the next one.
+const BackupSuffix = ".godotreleaser.bak"
+
+// windowsPlatform is the platform of presets with file and product versions.
+const windowsPlatform = "Windows Desktop"
+
+type Options struct {
+	// Version is the release version, such as "1.2.0". Nothing is stamped if
+	// it is empty.
+	Version string
+	// Presets also stamps the version into the application/file_version and
+	// application/product_version options of Windows presets.
+	Presets bool
+}
+
+// Stamp is a set of stamped files, which Restore puts back as they were.
+type Stamp struct {
+	fs    afero.Fs
+	files []original
+}
+
+type original struct {
+	path string
+	data []byte
+}
+
+// Apply stamps the version into the project.godot, and optionally the
+// export_presets.cfg, of a project. Backups left behind by earlier builds are
+// restored first, even if there is nothing to stamp.
+func Apply(fs afero.Fs, projectDir string, opts Options) (*Stamp, error) {
+	project := filepath.Join(projectDir, "project.godot")
+	presets := filepath.Join(projectDir, "export_presets.cfg")
+
+	for _, path := range []string{project, presets} {
+		if err := recoverBackup(fs, path); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Stamp{fs: fs}
+
+	if opts.Version == "" {
+		return s, nil
+	}
+
+	// export_presets.cfg doesn't say which version of Godot wrote it.
+	var godot3 bool
+
+	err := s.edit(project, func(d *document.Document) {
+		godot3 = d.Godot3()
+		d.Set("application", "config/version", opts.Version)
+	})
+	if err != nil {
+		return nil, errors.Join(err, s.Restore())
+	}
+
+	slog.Info("Stamped release version", "version", opts.Version, "path", project)
+
+	if !opts.Presets {
+		return s, nil
+	}
+
+	fileVersion, err := FileVersion(opts.Version)
+	if err != nil {
+		slog.Warn("Not stamping export presets, as the release version is not numeric", "version", opts.Version, "error", err)
+
+		return s, nil
+	}
+
+	err = s.edit(presets, func(d *document.Document) {
+		d.SetGodot3(godot3)
+
+		for _, section := range d.Sections() {
+			if platform, _ := d.Get(section, "platform"); platform != windowsPlatform {
+				continue
+			}
+
+			d.Set(section+".options", "application/file_version", fileVersion)
+			d.Set(section+".options", "application/product_version", fileVersion)
+		}
+	})
+	if err != nil {
+		return nil, errors.Join(err, s.Restore())
+	}
+
+	slog.Info("Stamped release version into Windows presets", "version", fileVersion, "path", presets)
+
+	return s, nil
+}
+
+// Restore puts the stamped files back as they were and removes their backups.
+func (s *Stamp) Restore() error {
+	var errs []error
+
+	for i := len(s.files) - 1; i >= 0; i-- {
+		f := s.files[i]
+
+		if err := writeFile(s.fs, f.path, f.data); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore %s, a copy is in %s: %w", f.path, f.path+BackupSuffix, err))
+
+			continue
+		}
+
+		if err := s.fs.Remove(f.path + BackupSuffix); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove backup of %s: %w", f.path, err))
+		}
+
+		slog.Debug("Restored stamped file", "path", f.path)
+	}
+
+	s.files = nil
+
+	return errors.Join(errs...)
+}
+
+// edit backs up a file before changing it.
+func (s *Stamp) edit(path string, change func(d *document.Document)) error {
+	data, err := afero.ReadFile(s.fs, path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	d, err := document.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if err := writeFile(s.fs, path+BackupSuffix, data); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+
+	s.files = append(s.files, original{path: path, data: data})
+
+	change(d)
+
+	return writeFile(s.fs, path, d.Bytes())
+}
+
+// recoverBackup restores a file from a backup left behind by a build that
+// didn't get the chance to restore it.
+func recoverBackup(fs afero.Fs, path string) error {
+	data, err := afero.ReadFile(fs, path+BackupSuffix)
+	if errors.Is(err, afero.ErrFileNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read backup of %s: %w", path, err)
+	}
+
+	slog.Warn("Restoring file left stamped by an earlier build", "path", path)
+
+	if err := writeFile(fs, path, data); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", path, err)
+	}
+
+	return fs.Remove(path + BackupSuffix) //nolint:wrapcheck
+}
+
+// writeFile writes a file, keeping its permissions if it already exists.
+func writeFile(fs afero.Fs, path string, data []byte) error {
+	mode := os.FileMode(0o644)
+
+	if info, err := fs.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	return afero.WriteFile(fs, path, data, mode) //nolint:wrapcheck
+}
+
+// FileVersion converts a version into the four numbers Windows expects for
+// file and product versions, such as "1.2.0.0" for "v1.2.0-beta".
+func FileVersion(v string) (string, error) {
+	parsed, err := version.NewVersion(v)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %q: %w", v, err)
+	}
+
+	segments := parsed.Segments64()
+	for len(segments) < 4 {
+		segments = append(segments, 0)
+	}
+
+	parts := make([]string, 4)
+	for i, n := range segments[:4] {
+		parts[i] = fmt.Sprint(n)
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
+// GitTag returns the tag of the commit checked out in a directory, without a
+// leading "v", or an empty string if it isn't tagged.
+func GitTag(ctx context.Context, dir string) string {
+	cmd := exec.CommandContext(ctx, "git", "describe", "--tags", "--exact-match", "HEAD")
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		slog.Debug("No git tag found for the release version", "dir", dir, "error", err)
+
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "v")
+}
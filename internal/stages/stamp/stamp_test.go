@@ -0,0 +1,177 @@
+package stamp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	projectFile = heredoc.Doc(`
+		config_version=5
+
+		[application]
+
+		config/name="Example"
+		config/features=PackedStringArray("4.3", "Forward Plus")
+	`)
+
+	presetsFile = heredoc.Doc(`
+		[preset.0]
+
+		name="Windows"
+		platform="Windows Desktop"
+
+		[preset.0.options]
+
+		application/file_version=""
+		application/product_version=""
+
+		[preset.1]
+
+		name="Linux"
+		platform="Linux"
+
+		[preset.1.options]
+
+		binary_format/architecture="x86_64"
+	`)
+)
+
+func newProject(t *testing.T) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/game/project.godot", []byte(projectFile), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/game/export_presets.cfg", []byte(presetsFile), 0o644))
+
+	return fs
+}
+
+func read(t *testing.T, fs afero.Fs, path string) string {
+	t.Helper()
+
+	data, err := afero.ReadFile(fs, path)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	fs := newProject(t)
+
+	s, err := Apply(fs, "/game", Options{Version: "1.2.0-beta.1", Presets: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, heredoc.Doc(`
+		config_version=5
+
+		[application]
+
+		config/name="Example"
+		config/features=PackedStringArray("4.3", "Forward Plus")
+		config/version="1.2.0-beta.1"
+	`), read(t, fs, "/game/project.godot"))
+
+	presets := read(t, fs, "/game/export_presets.cfg")
+	assert.Contains(t, presets, "application/file_version=\"1.2.0.0\"\napplication/product_version=\"1.2.0.0\"\n")
+	assert.Equal(t, 1, strings.Count(presets, "application/file_version"), "only Windows presets are stamped")
+
+	assert.Equal(t, projectFile, read(t, fs, "/game/project.godot"+BackupSuffix))
+
+	require.NoError(t, s.Restore())
+
+	assert.Equal(t, projectFile, read(t, fs, "/game/project.godot"))
+	assert.Equal(t, presetsFile, read(t, fs, "/game/export_presets.cfg"))
+
+	for _, path := range []string{"/game/project.godot", "/game/export_presets.cfg"} {
+		exists, err := afero.Exists(fs, path+BackupSuffix)
+		require.NoError(t, err)
+		assert.False(t, exists, path)
+	}
+}
+
+func TestApply_WithoutPresets(t *testing.T) {
+	t.Parallel()
+
+	fs := newProject(t)
+
+	s, err := Apply(fs, "/game", Options{Version: "2.0.0"})
+	require.NoError(t, err)
+
+	assert.Contains(t, read(t, fs, "/game/project.godot"), `config/version="2.0.0"`)
+	assert.Equal(t, presetsFile, read(t, fs, "/game/export_presets.cfg"))
+
+	require.NoError(t, s.Restore())
+	assert.Equal(t, projectFile, read(t, fs, "/game/project.godot"))
+}
+
+func TestApply_NoVersion(t *testing.T) {
+	t.Parallel()
+
+	fs := newProject(t)
+
+	s, err := Apply(fs, "/game", Options{Presets: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, projectFile, read(t, fs, "/game/project.godot"))
+	require.NoError(t, s.Restore())
+}
+
+func TestApply_RecoversBackup(t *testing.T) {
+	t.Parallel()
+
+	fs := newProject(t)
+
+	// A build that was killed leaves the stamped file and its backup behind.
+	require.NoError(t, afero.WriteFile(fs, "/game/project.godot", []byte("stamped"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/game/project.godot"+BackupSuffix, []byte(projectFile), 0o644))
+
+	s, err := Apply(fs, "/game", Options{})
+	require.NoError(t, err)
+	require.NoError(t, s.Restore())
+
+	assert.Equal(t, projectFile, read(t, fs, "/game/project.godot"))
+
+	exists, err := afero.Exists(fs, "/game/project.godot"+BackupSuffix)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestApply_InvalidProject(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/game/project.godot", []byte("[application\n"), 0o644))
+
+	_, err := Apply(fs, "/game", Options{Version: "1.0.0"})
+	require.Error(t, err)
+
+	assert.Equal(t, "[application\n", read(t, fs, "/game/project.godot"))
+}
+
+func TestFileVersion(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]string{
+		"1":            "1.0.0.0",
+		"1.2":          "1.2.0.0",
+		"v1.2.3":       "1.2.3.0",
+		"1.2.3-beta.1": "1.2.3.0",
+		"1.2.3.4":      "1.2.3.4",
+		"1.2.3.4.5":    "1.2.3.4",
+	} {
+		got, err := FileVersion(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := FileVersion("latest")
+	assert.Error(t, err)
+}
@@ -0,0 +1,335 @@
+// Package validate checks a project for problems that would make an export
+// fail, or produce a broken game, before any time is spent exporting it.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ruffel/godotreleaser/internal/godot/engine"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/document"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/exports"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/project"
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+)
+
+// ErrFailed is returned when a project has errors.
+var ErrFailed = errors.New("project has validation errors")
+
+type Severity string
+
+const (
+	// Error is a problem that stops the project from being exported or run.
+	Error Severity = "error"
+	// Warning is a likely mistake that doesn't stop the export.
+	Warning Severity = "warning"
+	// Info is worth knowing, but not a problem.
+	Info Severity = "info"
+)
+
+// Finding is a problem found by a check.
+type Finding struct {
+	Severity Severity
+	// Check names the check, such as "main-scene".
+	Check   string
+	Message string
+}
+
+type Options struct {
+	// Version is the version of Godot the project will be exported with, if
+	// it is known.
+	Version string
+}
+
+// Run checks the project in a directory. An error is only returned if the
+// project can't be checked at all; problems with it are returned as findings.
+func Run(fs afero.Fs, projectDir string, opts Options) ([]Finding, error) {
+	data, err := afero.ReadFile(fs, filepath.Join(projectDir, "project.godot"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project.godot: %w", err)
+	}
+
+	config, err := project.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("project.godot is not valid: %w", err)
+	}
+
+	doc, err := document.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("project.godot is not valid: %w", err)
+	}
+
+	v := &validator{fs: fs, dir: projectDir}
+
+	v.mainScene(config)
+	v.icon(config)
+	v.autoloads(config)
+	v.projectPaths(doc)
+	v.configVersion(config, opts.Version)
+	v.presets()
+
+	return v.findings, nil
+}
+
+// HasErrors reports whether any of the findings is an error.
+func HasErrors(findings []Finding) bool {
+	return lo.ContainsBy(findings, func(f Finding) bool { return f.Severity == Error })
+}
+
+// checkedSettings are checked on their own, so they are skipped when checking
+// the rest of the paths in project.godot.
+var checkedSettings = []string{"application/run/main_scene", "application/config/icon"} //nolint:gochecknoglobals
+
+type validator struct {
+	fs       afero.Fs
+	dir      string
+	findings []Finding
+	// uids maps the uid:// paths of the project to res:// paths. It is built
+	// the first time one is needed.
+	uids map[string]string
+}
+
+func (v *validator) add(severity Severity, check, format string, args ...any) {
+	v.findings = append(v.findings, Finding{Severity: severity, Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) mainScene(config *project.Config) {
+	if config.MainScene == "" {
+		v.add(Warning, "main-scene", "no main scene is set, so the exported game won't start")
+
+		return
+	}
+
+	if err := v.exists(config.MainScene); err != nil {
+		v.add(Error, "main-scene", "main scene %s", err)
+	}
+}
+
+func (v *validator) icon(config *project.Config) {
+	if config.Icon() == "" {
+		v.add(Warning, "icon", "no icon is set, so the exported game uses Godot's")
+
+		return
+	}
+
+	if err := v.exists(config.Icon()); err != nil {
+		v.add(Error, "icon", "icon %s", err)
+	}
+}
+
+func (v *validator) autoloads(config *project.Config) {
+	for _, autoload := range config.Autoloads() {
+		if err := v.exists(autoload.Path); err != nil {
+			v.add(Error, "autoload", "autoload %s: %s", autoload.Name, err)
+		}
+	}
+}
+
+// projectPaths checks every other res:// path in project.godot, including
+// those in arrays and dictionaries.
+func (v *validator) projectPaths(doc *document.Document) {
+	for _, section := range doc.Raw() {
+		if section.Name == "autoload" {
+			continue
+		}
+
+		for i, key := range section.Keys {
+			setting := lo.Ternary(section.Name == document.Global, key, section.Name+"/"+key)
+			if lo.Contains(checkedSettings, setting) {
+				continue
+			}
+
+			value, err := variant.Parse(section.Values[i])
+			if err != nil {
+				v.add(Warning, "project-paths", "%s has an invalid value: %s", setting, err)
+
+				continue
+			}
+
+			for _, p := range resourcePaths(value) {
+				if err := v.exists(p); err != nil {
+					v.add(Error, "project-paths", "%s: %s", setting, err)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) configVersion(config *project.Config, version string) {
+	if version == "" {
+		v.add(Info, "config-version", "the Godot version is unknown, so config_version %d was not checked", config.Version)
+
+		return
+	}
+
+	if !engine.IsGodot3(version) && config.Version != project.ConfigVersionGodot4 {
+		v.add(Error, "config-version", "config_version is %d, but Godot %s expects %d", config.Version, version, project.ConfigVersionGodot4)
+	}
+
+	// Godot 3.0 writes 3, and later Godot 3 editors 4.
+	if engine.IsGodot3(version) && !config.IsGodot3() {
+		v.add(Error, "config-version", "config_version is %d, but Godot %s expects 3 or 4", config.Version, version)
+	}
+}
+
+func (v *validator) presets() {
+	data, err := afero.ReadFile(v.fs, filepath.Join(v.dir, "export_presets.cfg"))
+	if err != nil {
+		v.add(Error, "export-presets", "export_presets.cfg cannot be read, add presets in the editor's Export dialog: %s", err)
+
+		return
+	}
+
+	e, err := exports.Parse(data)
+
+	switch {
+	case errors.Is(err, exports.ErrNoPresets):
+		v.add(Error, "export-presets", "export_presets.cfg has no presets")
+
+		return
+	case err != nil:
+		v.add(Error, "export-presets", "export_presets.cfg is not valid: %s", err)
+
+		return
+	}
+
+	for _, preset := range e.Presets() {
+		if strings.TrimSpace(preset.ExportPath) == "" {
+			v.add(Error, "export-path", "preset %q has no export path", preset.Name)
+		}
+	}
+}
+
+// exists checks that a res:// or uid:// path points at a file or directory,
+// returning an error that describes the problem if it doesn't.
+func (v *validator) exists(p string) error {
+	if strings.HasPrefix(p, "uid://") {
+		resolved, ok := v.uid(p)
+		if !ok {
+			return fmt.Errorf("%s does not match any file", p)
+		}
+
+		p = resolved
+	}
+
+	if !strings.HasPrefix(p, "res://") {
+		return fmt.Errorf("%s is not a res:// path", p)
+	}
+
+	// Sub-resources are written as res://path::id.
+	local, _, _ := strings.Cut(strings.TrimPrefix(p, "res://"), "::")
+
+	ok, err := afero.Exists(v.fs, filepath.Join(v.dir, filepath.FromSlash(local)))
+	if err != nil {
+		return fmt.Errorf("%s cannot be checked: %w", p, err)
+	}
+
+	if !ok {
+		return fmt.Errorf("%s does not exist", p)
+	}
+
+	return nil
+}
+
+var uidPattern = regexp.MustCompile(`uid="(uid://[0-9a-z]+)"`) //nolint:gochecknoglobals
+
+// uid resolves a uid:// path from the uid="..." attributes of scenes, resources
+// and .import files, and the .uid files written next to scripts by Godot 4.4.
+func (v *validator) uid(uid string) (string, bool) {
+	if v.uids == nil {
+		v.uids = map[string]string{}
+
+		_ = afero.Walk(v.fs, v.dir, func(p string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return nil //nolint:nilerr
+			}
+
+			if info.IsDir() {
+				return lo.Ternary(p != v.dir && strings.HasPrefix(info.Name(), "."), filepath.SkipDir, nil)
+			}
+
+			v.indexUID(p)
+
+			return nil
+		})
+	}
+
+	p, ok := v.uids[uid]
+
+	return p, ok
+}
+
+func (v *validator) indexUID(p string) {
+	ext := path.Ext(p)
+	if !lo.Contains([]string{".tscn", ".tres", ".import", ".uid"}, ext) {
+		return
+	}
+
+	data, err := afero.ReadFile(v.fs, p)
+	if err != nil {
+		return
+	}
+
+	rel, err := filepath.Rel(v.dir, p)
+	if err != nil {
+		return
+	}
+
+	target := "res://" + filepath.ToSlash(rel)
+
+	switch ext {
+	case ".uid":
+		v.uids[strings.TrimSpace(string(data))] = strings.TrimSuffix(target, ext)
+	case ".import":
+		if m := uidPattern.FindSubmatch(data); m != nil {
+			v.uids[string(m[1])] = strings.TrimSuffix(target, ext)
+		}
+	default:
+		// The uid of a scene or resource is in its first line.
+		line, _, _ := strings.Cut(string(data), "\n")
+		if m := uidPattern.FindStringSubmatch(line); m != nil {
+			v.uids[m[1]] = target
+		}
+	}
+}
+
+// resourcePaths returns the res:// paths in a value. Filters such as
+// res://*.json are not paths.
+func resourcePaths(value any) []string {
+	var paths []string
+
+	var walk func(any)
+
+	walk = func(value any) {
+		switch value := value.(type) {
+		case string:
+			if strings.HasPrefix(value, "res://") && !strings.Contains(value, "*") {
+				paths = append(paths, value)
+			}
+		case []string:
+			for _, s := range value {
+				walk(s)
+			}
+		case []any:
+			for _, item := range value {
+				walk(item)
+			}
+		case variant.Dictionary:
+			for _, kv := range value {
+				walk(kv.Key)
+				walk(kv.Value)
+			}
+		}
+	}
+
+	walk(value)
+
+	return paths
+}
@@ -0,0 +1,200 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, "/game/"+path, []byte(content), 0o644))
+	}
+
+	return fs
+}
+
+const validPresets = `[preset.0]
+
+name="Linux"
+platform="Linux"
+export_path="build/game.x86_64"
+`
+
+func TestRun_Valid(t *testing.T) {
+	t.Parallel()
+
+	fs := writeFiles(t, map[string]string{
+		"project.godot": heredoc.Doc(`
+			config_version=5
+
+			[application]
+
+			config/name="Game"
+			run/main_scene="uid://b8x5b0d8ukwk1"
+			config/icon="res://icon.svg"
+
+			[autoload]
+
+			Events="*res://autoload/events.gd"
+			Music="uid://c2ml0ma8d3l6j"
+
+			[editor_plugins]
+
+			enabled=PackedStringArray("res://addons/gut/plugin.cfg")
+
+			[internationalization]
+
+			locale/translations=PackedStringArray("res://i18n/text.en.translation")
+
+			[rendering]
+
+			environment/defaults/default_environment="res://env.tres::Environment_1"
+		`),
+		"main.tscn":                    "[gd_scene format=3 uid=\"uid://b8x5b0d8ukwk1\"]\n",
+		"icon.svg":                     "<svg/>",
+		"autoload/events.gd":           "extends Node\n",
+		"autoload/music.gd":            "extends Node\n",
+		"autoload/music.gd.uid":        "uid://c2ml0ma8d3l6j\n",
+		"addons/gut/plugin.cfg":        "[plugin]\n",
+		"i18n/text.en.translation":     "",
+		"env.tres":                     "[gd_resource type=\"Environment\" format=3]\n",
+		"export_presets.cfg":           validPresets,
+		".godot/imported/ignored.tscn": "[gd_scene format=3 uid=\"uid://b8x5b0d8ukwk1\"]\n",
+	})
+
+	findings, err := Run(fs, "/game", Options{Version: "4.4"})
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestRun_Problems(t *testing.T) {
+	t.Parallel()
+
+	fs := writeFiles(t, map[string]string{
+		"project.godot": heredoc.Doc(`
+			config_version=4
+
+			[application]
+
+			config/name="Game"
+			run/main_scene="res://missing.tscn"
+			config/icon="uid://doesnotexist"
+
+			[autoload]
+
+			Events="*res://autoload/events.gd"
+
+			[gui]
+
+			theme/custom="res://theme.tres"
+			theme/fallbacks=["res://exists.tres", "res://gone.tres"]
+
+			[filters]
+
+			include="res://*.json"
+		`),
+		"exists.tres": "",
+		"export_presets.cfg": heredoc.Doc(`
+			[preset.0]
+
+			name="Windows"
+			platform="Windows Desktop"
+			export_path=""
+		`),
+	})
+
+	findings, err := Run(fs, "/game", Options{Version: "4.3"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []Finding{
+		{Severity: Error, Check: "main-scene", Message: "main scene res://missing.tscn does not exist"},
+		{Severity: Error, Check: "icon", Message: "icon uid://doesnotexist does not match any file"},
+		{Severity: Error, Check: "autoload", Message: "autoload Events: res://autoload/events.gd does not exist"},
+		{Severity: Error, Check: "project-paths", Message: "gui/theme/custom: res://theme.tres does not exist"},
+		{Severity: Error, Check: "project-paths", Message: "gui/theme/fallbacks: res://gone.tres does not exist"},
+		{Severity: Error, Check: "config-version", Message: "config_version is 4, but Godot 4.3 expects 5"},
+		{Severity: Error, Check: "export-path", Message: `preset "Windows" has no export path`},
+	}, findings)
+	assert.True(t, HasErrors(findings))
+}
+
+func TestRun_Presets(t *testing.T) {
+	t.Parallel()
+
+	project := heredoc.Doc(`
+		config_version=4
+
+		[application]
+
+		config/name="Game"
+	`)
+
+	tests := []struct {
+		name    string
+		presets *string
+		message string
+	}{
+		{name: "missing", message: "export_presets.cfg cannot be read"},
+		{name: "empty", presets: new(string), message: "export_presets.cfg has no presets"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			files := map[string]string{"project.godot": project}
+			if tt.presets != nil {
+				files["export_presets.cfg"] = *tt.presets
+			}
+
+			findings, err := Run(writeFiles(t, files), "/game", Options{})
+			require.NoError(t, err)
+
+			checks := map[string]Finding{}
+			for _, f := range findings {
+				checks[f.Check] = f
+			}
+
+			assert.Equal(t, Warning, checks["main-scene"].Severity)
+			assert.Equal(t, Warning, checks["icon"].Severity)
+			assert.Equal(t, Info, checks["config-version"].Severity)
+			assert.Equal(t, Error, checks["export-presets"].Severity)
+			assert.Contains(t, checks["export-presets"].Message, tt.message)
+		})
+	}
+}
+
+func TestRun_InvalidProject(t *testing.T) {
+	t.Parallel()
+
+	_, err := Run(writeFiles(t, map[string]string{"project.godot": "[application\n"}), "/game", Options{})
+	assert.Error(t, err)
+}
+
+func TestRun_Godot3ConfigVersions(t *testing.T) {
+	t.Parallel()
+
+	corpus := afero.NewReadOnlyFs(afero.NewBasePathFs(afero.NewOsFs(), "../../../pkg/godot/config/parser/testdata/conformance/handwritten/godot3"))
+
+	// Godot 3.0 wrote config_version=3, and 3.1 and later 4.
+	for dir, version := range map[string]string{"/v3.0": "3.0.6", "/v3.2": "3.2.3"} {
+		findings, err := Run(corpus, dir, Options{Version: version})
+		require.NoError(t, err)
+
+		for _, f := range findings {
+			assert.NotEqual(t, "config-version", f.Check, "%s: %s", dir, f.Message)
+		}
+	}
+
+	findings, err := Run(corpus, "/v3.0", Options{Version: "4.3"})
+	require.NoError(t, err)
+	assert.Contains(t, findings, Finding{Severity: Error, Check: "config-version", Message: "config_version is 3, but Godot 4.3 expects 5"})
+}
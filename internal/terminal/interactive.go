@@ -0,0 +1,17 @@
+package terminal
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Interactive reports whether output goes to a terminal that can redraw
+// progress bars in place. CI logs and redirected output get plain lines.
+func Interactive() bool {
+	if os.Getenv("CI") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
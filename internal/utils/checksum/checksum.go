@@ -0,0 +1,60 @@
+package checksum
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// SHA256File returns the hex encoded SHA-256 digest of the file at path.
+func SHA256File(path string) (string, error) {
+	return hashFile(path, sha256.New())
+}
+
+// SHA512File returns the hex encoded SHA-512 digest of the file at path.
+func SHA512File(path string) (string, error) {
+	return hashFile(path, sha512.New())
+}
+
+func hashFile(path string, h hash.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ParseSums parses a checksum listing in the format produced by sha512sum and
+// friends ("<digest>  <filename>"), keyed by the base name of each file.
+func ParseSums(data []byte) map[string]string {
+	sums := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 { //nolint:mnd
+			continue
+		}
+
+		// Binary mode listings prefix the filename with an asterisk.
+		name := strings.TrimPrefix(fields[1], "*")
+
+		sums[path.Base(name)] = strings.ToLower(fields[0])
+	}
+
+	return sums
+}
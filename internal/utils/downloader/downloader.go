@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/ruffel/godotreleaser/internal/utils/checksum"
 )
 
 const (
@@ -17,13 +21,25 @@ const (
 	defaultFilePermissions = 0o644
 	defaultBufferSize      = 32 * 1024
 	defaultInterval        = 500 * time.Millisecond
+	defaultSimpleInterval  = 5 * time.Second
 )
 
+// ErrChecksumMismatch is returned when a downloaded file doesn't have the
+// expected digest.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
 // ProgressTracker defines an interface for tracking download progress.
 type ProgressTracker interface {
 	Update(downloaded int64, total int64)
 }
 
+// ProgressFinisher is implemented by trackers that need to know when a
+// download is complete, which Update cannot tell them if the length of the
+// file is unknown.
+type ProgressFinisher interface {
+	Finish()
+}
+
 // Option defines a function type for setting download options.
 type Option func(*downloadOptions)
 
@@ -33,6 +49,9 @@ type downloadOptions struct {
 	bufferSize      int
 	filePerm        os.FileMode
 	retries         int
+	resume          bool
+	connections     int
+	sha512          string
 }
 
 // defaultDownloadOptions returns a new downloadOptions with default values.
@@ -43,6 +62,8 @@ func defaultDownloadOptions() *downloadOptions {
 		bufferSize:      defaultBufferSize,
 		filePerm:        defaultFilePermissions,
 		retries:         defaultRetries,
+		resume:          true,
+		connections:     1,
 	}
 }
 
@@ -81,7 +102,42 @@ func WithRetries(retries int) Option {
 	}
 }
 
+// WithResume controls whether partial downloads are kept on failure and
+// resumed by later calls. Resuming is enabled by default.
+func WithResume(resume bool) Option {
+	return func(d *downloadOptions) {
+		d.resume = resume
+	}
+}
+
+// WithConnections sets the number of concurrent connections used to fetch the
+// file in ranges, if the server supports it. Files smaller than a megabyte per
+// connection use fewer connections.
+func WithConnections(n int) Option {
+	return func(d *downloadOptions) {
+		d.connections = n
+	}
+}
+
+// WithSHA512 sets the expected hex encoded SHA-512 digest of the file. The
+// download is checked against it before being moved into place.
+func WithSHA512(sum string) Option {
+	return func(d *downloadOptions) {
+		d.sha512 = strings.ToLower(sum)
+	}
+}
+
 // DownloadFile downloads a file from the specified URL to the given path.
+//
+// While the transfer is in progress the content is written to a partial file
+// next to the destination. If a previous attempt left a partial file behind,
+// the download resumes from where it stopped, provided the server supports
+// range requests and the remote file has not changed in the meantime.
+//
+// With more than one connection, a fresh download is split into ranges that
+// are fetched concurrently, and resumed the same way. Servers that don't
+// support ranges are downloaded over a single connection instead, keeping any
+// data from an earlier parallel download up to its first gap.
 func DownloadFile(ctx context.Context, url string, path string, opt ...Option) error {
 	if err := validateInputParameters(ctx, url, path); err != nil {
 		return err
@@ -102,44 +158,46 @@ func DownloadFile(ctx context.Context, url string, path string, opt ...Option) e
 		return fmt.Errorf("creating directories for %s: %w", absPath, err)
 	}
 
-	// Perform the HTTP request with retries.
-	res, err := executeRequestWithRetries(ctx, url, opts)
-	if err != nil {
-		return fmt.Errorf("performing HTTP request: %w", err)
+	part := newPartialFile(absPath)
+
+	if !opts.resume {
+		part.discard()
 	}
 
-	// Create a temporary file for downloading
-	tempFile, err := os.CreateTemp(filepath.Dir(absPath), "downloader-*")
-	if err != nil {
-		res.Body.Close()
+	err = errRangesUnsupported
 
-		return fmt.Errorf("creating temporary file for %s: %w", absPath, err)
+	// A partial parallel download is resumed in parallel, while one from a
+	// single connection is resumed over one connection.
+	if offset, state := part.resumeState(url); opts.connections > 1 && (offset == 0 || state.parallel()) {
+		err = downloadParallel(ctx, url, part, opts)
 	}
 
-	// Ensure the temp file is closed properly even if an error occurs.
-	defer func() {
-		tempFile.Close()
-		// Remove the temp file if an error occurred.
-		if err != nil {
-			os.Remove(tempFile.Name())
+	// Perform the download with retries, resuming after each failed attempt.
+	if errors.Is(err, errRangesUnsupported) {
+		if err := part.linearize(url); err != nil {
+			return err
 		}
-	}()
 
-	// Perform the download
-	if err := performDownload(ctx, res, tempFile, opts); err != nil {
-		// Cleanup temp file on error
-		tempFile.Close()
-		os.Remove(tempFile.Name())
+		err = downloadWithRetries(ctx, url, part, opts)
+	}
+
+	if err != nil {
+		if !opts.resume {
+			part.discard()
+		}
 
 		return err
 	}
-	defer res.Body.Close()
 
 	// Finalize the download
-	if err := finalizeDownload(tempFile, absPath, opts); err != nil {
+	if err := finalizeDownload(part, absPath, opts); err != nil {
 		return err
 	}
 
+	if finisher, ok := opts.progressTracker.(ProgressFinisher); ok {
+		finisher.Finish()
+	}
+
 	return nil
 }
 
@@ -160,88 +218,134 @@ func validateInputParameters(ctx context.Context, url, path string) error {
 	return nil
 }
 
-// executeRequestWithRetries performs the HTTP request with retry logic.
-//
-// nolint:cyclop
-func executeRequestWithRetries(ctx context.Context, url string, opts *downloadOptions) (*http.Response, error) {
-	var res *http.Response
-
+// downloadWithRetries performs download attempts with exponential backoff
+// between them. Each attempt picks up from the data already on disk.
+func downloadWithRetries(ctx context.Context, url string, part *partialFile, opts *downloadOptions) error {
 	var err error
 
-	// Create a new HTTP GET request.
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating HTTP request: %w", err)
-	}
-
 	for attempt := range opts.retries {
-		// Perform the HTTP request
-		res, err = opts.httpClient.Do(req)
-		if err == nil && (res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices) {
-			err = fmt.Errorf("received non-success HTTP status code %d: %s", res.StatusCode, res.Status)
-		}
-
-		if err == nil {
-			return res, nil
+		if err = downloadAttempt(ctx, url, part, opts); err == nil {
+			return nil
 		}
 
-		// Close response body if it was opened
-		if res != nil {
-			res.Body.Close()
+		// Cancellation is never transient.
+		if ctx.Err() != nil {
+			return ctx.Err() //nolint:wrapcheck
 		}
 
 		// Check if we should retry
 		if attempt < opts.retries-1 {
-			// Exponential backoff
-			backoff := time.Duration(math.Pow(2, float64(attempt))) * time.Second //nolint:mnd
-			select {
-			case <-time.After(backoff):
-				continue
-			case <-ctx.Done():
-				return nil, ctx.Err() //nolint:wrapcheck
+			if err := backoff(ctx, attempt); err != nil {
+				return err
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("failed to download %s after %d attempts: %w", url, opts.retries, err)
+	return fmt.Errorf("failed to download %s after %d attempts: %w", url, opts.retries, err)
 }
 
-// performDownload handles the actual downloading of the file content.
-func performDownload(ctx context.Context, res *http.Response, tempFile *os.File, opts *downloadOptions) error {
-	length := res.ContentLength // Can be -1 if the length is unknown.
+// backoff waits exponentially longer after each failed attempt.
+func backoff(ctx context.Context, attempt int) error {
+	select {
+	case <-time.After(time.Duration(math.Pow(2, float64(attempt))) * time.Second): //nolint:mnd
+		return nil
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck
+	}
+}
 
-	// Wrap the response body with a context-aware reader
-	bodyReader := newContextReader(ctx, res.Body)
+// downloadAttempt performs a single HTTP request and writes the response body
+// to the partial file, resuming from the existing partial content if possible.
+//
+//nolint:cyclop,funlen
+func downloadAttempt(ctx context.Context, url string, part *partialFile, opts *downloadOptions) error {
+	offset, state := part.resumeState(url)
 
-	// Create a progress writer
-	progress := &progressWriter{
-		total:    length,
-		callback: opts.progressTracker,
+	// Create a new HTTP GET request.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating HTTP request: %w", err)
 	}
 
-	// Create a TeeReader to track progress
-	reader := io.TeeReader(bodyReader, progress)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", state.validator())
+	}
 
-	// Copy the data to the temp file
-	if _, err := io.CopyBuffer(tempFile, reader, make([]byte, opts.bufferSize)); err != nil {
-		return fmt.Errorf("downloading content: %w", err)
+	res, err := opts.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("performing HTTP request: %w", err)
 	}
+	defer res.Body.Close()
 
-	return nil
+	switch {
+	case res.StatusCode == http.StatusPartialContent && offset > 0:
+		start, total, err := parseContentRange(res.Header.Get("Content-Range"))
+		if err != nil || start != offset || !state.matches(res.Header) {
+			// The server answered with something we didn't ask for. Throw the
+			// partial content away and let the next attempt start over.
+			part.discard()
+
+			return fmt.Errorf("unexpected partial response (Content-Range %q)", res.Header.Get("Content-Range"))
+		}
+
+		slog.Debug("Resuming download", "url", url, "offset", offset, "total", total)
+
+		return part.write(ctx, res.Body, offset, total, opts)
+
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// Either we already have everything, or the remote file shrank.
+		_, total, _ := parseContentRange(res.Header.Get("Content-Range"))
+		if total == offset {
+			return nil
+		}
+
+		part.discard()
+
+		return fmt.Errorf("received non-success HTTP status code %d: %s", res.StatusCode, res.Status)
+
+	case res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices:
+		return fmt.Errorf("received non-success HTTP status code %d: %s", res.StatusCode, res.Status)
+	}
+
+	// A full response: either this is a fresh download, the server doesn't
+	// support ranges, or the remote file changed since the partial was written.
+	if offset > 0 {
+		slog.Debug("Server sent the full file, restarting download", "url", url, "discarded", offset)
+	}
+
+	if err := part.reset(newPartialState(url, res.Header)); err != nil {
+		return err
+	}
+
+	return part.write(ctx, res.Body, 0, res.ContentLength, opts)
 }
 
-// finalizeDownload renames the temporary file and sets file permissions.
-func finalizeDownload(tempFile *os.File, absPath string, opts *downloadOptions) error {
-	// Close the temp file
-	if err := tempFile.Close(); err != nil {
-		return fmt.Errorf("closing temporary file %s: %w", tempFile.Name(), err)
+// finalizeDownload verifies the partial file, renames it and sets file
+// permissions.
+func finalizeDownload(part *partialFile, absPath string, opts *downloadOptions) error {
+	if opts.sha512 != "" {
+		sum, err := checksum.SHA512File(part.path)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		if sum != opts.sha512 {
+			// Resuming would only reproduce the same file.
+			part.discard()
+
+			return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, filepath.Base(absPath), opts.sha512, sum)
+		}
 	}
 
-	// Rename the temporary file to the final path.
-	if err := os.Rename(tempFile.Name(), absPath); err != nil {
-		return fmt.Errorf("moving temporary file to %s: %w", absPath, err)
+	// Rename the partial file to the final path.
+	if err := os.Rename(part.path, absPath); err != nil {
+		return fmt.Errorf("moving partial file to %s: %w", absPath, err)
 	}
 
+	// The resume metadata is meaningless once the file is complete.
+	_ = os.Remove(part.metaPath)
+
 	// Set file permissions after renaming
 	if err := os.Chmod(absPath, opts.filePerm); err != nil {
 		return fmt.Errorf("setting permissions for %s: %w", absPath, err)
@@ -297,22 +401,98 @@ func (w *progressWriter) Write(p []byte) (int, error) {
 	return n, nil
 }
 
+// flush reports the current progress regardless of the reporting interval.
+func (w *progressWriter) flush() {
+	if w.callback != nil {
+		w.callback.Update(w.downloaded, w.total)
+	}
+}
+
+// SimpleTracker reports progress as a line of text at regular intervals, for
+// output that isn't a terminal such as CI logs.
 type SimpleTracker struct {
-	w     io.Writer
-	title string
+	w        io.Writer
+	title    string
+	interval time.Duration
+
+	started     time.Time
+	startOffset int64
+	lastPrinted time.Time
+	downloaded  int64
+	total       int64
+	finished    bool
 }
 
-func NewSimpleTracker(w io.Writer, title string) ProgressTracker {
-	return &SimpleTracker{w: w, title: title}
+// NewSimpleTracker returns a tracker that writes a progress line to w every
+// few seconds, and once more when the download completes.
+func NewSimpleTracker(w io.Writer, title string) *SimpleTracker {
+	return &SimpleTracker{w: w, title: title, interval: defaultSimpleInterval}
+}
+
+// WithInterval sets how often a progress line is written.
+func (t *SimpleTracker) WithInterval(interval time.Duration) *SimpleTracker {
+	t.interval = interval
+
+	return t
 }
 
 func (t *SimpleTracker) Update(downloaded int64, total int64) {
-	dl := float64(downloaded)
-	tl := float64(total)
+	now := time.Now()
+
+	if t.started.IsZero() {
+		// Resumed downloads don't start at zero, which would inflate the speed.
+		t.started, t.startOffset, t.lastPrinted = now, downloaded, now
+	}
+
+	t.downloaded, t.total = downloaded, total
+
+	complete := total >= 0 && downloaded >= total
+	if t.finished || (!complete && now.Sub(t.lastPrinted) < t.interval) {
+		return
+	}
+
+	t.lastPrinted, t.finished = now, complete
+
+	fmt.Fprintln(t.w, t.line(downloaded, total, now.Sub(t.started)))
+}
+
+// Finish writes the completion line, if Update hasn't already because the
+// length of the file was unknown.
+func (t *SimpleTracker) Finish() {
+	if t.finished || t.started.IsZero() {
+		return
+	}
 
-	percentage := dl / tl * 100      //nolint:mnd
-	downloadedMB := dl / 1024 / 1024 //nolint:mnd
-	totalMB := tl / 1024 / 1024      //nolint:mnd
+	t.finished = true
+
+	fmt.Fprintln(t.w, t.line(t.downloaded, t.downloaded, time.Since(t.started)))
+}
+
+// line formats the progress, leaving out what cannot be known when the server
+// didn't send a content length.
+func (t *SimpleTracker) line(downloaded int64, total int64, elapsed time.Duration) string {
+	const mb = 1024 * 1024
+
+	speed := 0.0
+	if elapsed > 0 {
+		speed = float64(downloaded-t.startOffset) / elapsed.Seconds()
+	}
+
+	if total <= 0 {
+		return fmt.Sprintf("%s: %.1f MB (%.1f MB/s)", t.title, float64(downloaded)/mb, speed/mb)
+	}
+
+	percentage := float64(downloaded) / float64(total) * 100 //nolint:mnd
+
+	if downloaded >= total {
+		return fmt.Sprintf("%s: 100%% (%.1f MB) in %s", t.title, float64(total)/mb, elapsed.Round(time.Second))
+	}
+
+	eta := "unknown"
+	if speed > 0 {
+		eta = time.Duration(float64(total-downloaded) / speed * float64(time.Second)).Round(time.Second).String()
+	}
 
-	fmt.Fprintf(t.w, "%s: %.2f%% (%.2f MB / %.2f MB)\r", t.title, percentage, downloadedMB, totalMB)
+	return fmt.Sprintf("%s: %.0f%% (%.1f / %.1f MB, %.1f MB/s, ETA %s)",
+		t.title, percentage, float64(downloaded)/mb, float64(total)/mb, speed/mb, eta)
 }
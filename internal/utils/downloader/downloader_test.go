@@ -0,0 +1,400 @@
+package downloader_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ruffel/godotreleaser/internal/utils/downloader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const etag = `"v1"`
+
+var content = bytes.Repeat([]byte("0123456789"), 10_000) //nolint:gochecknoglobals
+
+// newServer serves content with range support, counting the bytes sent.
+func newServer(t *testing.T, ranges bool, sent *atomic.Int64) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ranges {
+			r.Header.Del("Range")
+		}
+
+		w.Header().Set("ETag", etag)
+
+		cw := &countingWriter{ResponseWriter: w, n: sent}
+		http.ServeContent(cw, r, "file", time.Unix(0, 0), bytes.NewReader(content))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n.Add(int64(len(p)))
+
+	return w.ResponseWriter.Write(p) //nolint:wrapcheck
+}
+
+// seedPartial leaves a partial download behind, as a failed attempt would.
+func seedPartial(t *testing.T, dst, url, tag string, n int) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(dst+".part", content[:n], 0o644))
+
+	meta, err := json.Marshal(map[string]string{"url": url, "etag": tag})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst+".part.json", meta, 0o644))
+}
+
+func TestDownloadFile_Fresh(t *testing.T) {
+	t.Parallel()
+
+	var sent atomic.Int64
+
+	srv := newServer(t, true, &sent)
+	dst := filepath.Join(t.TempDir(), "file.zip")
+
+	require.NoError(t, downloader.DownloadFile(context.Background(), srv.URL, dst))
+
+	got, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.NoFileExists(t, dst+".part")
+	assert.NoFileExists(t, dst+".part.json")
+}
+
+func TestDownloadFile_Resume(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		ranges   bool
+		etag     string
+		wantSent int64
+	}{
+		{
+			name:     "resumes matching partial",
+			ranges:   true,
+			etag:     etag,
+			wantSent: int64(len(content) - 40_000),
+		},
+		{
+			name:     "restarts when remote changed",
+			ranges:   true,
+			etag:     `"v0"`,
+			wantSent: int64(len(content)),
+		},
+		{
+			name:     "restarts without range support",
+			ranges:   false,
+			etag:     etag,
+			wantSent: int64(len(content)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var sent atomic.Int64
+
+			srv := newServer(t, tt.ranges, &sent)
+			dst := filepath.Join(t.TempDir(), "file.zip")
+
+			seedPartial(t, dst, srv.URL, tt.etag, 40_000)
+
+			require.NoError(t, downloader.DownloadFile(context.Background(), srv.URL, dst))
+
+			got, err := os.ReadFile(dst)
+			require.NoError(t, err)
+			assert.Equal(t, content, got)
+			assert.Equal(t, tt.wantSent, sent.Load())
+		})
+	}
+}
+
+func TestDownloadFile_KeepsPartialOnFailure(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Length", "100000")
+		_, _ = w.Write([]byte(strings.Repeat("x", 1000)))
+	}))
+	t.Cleanup(srv.Close)
+
+	dst := filepath.Join(t.TempDir(), "file.zip")
+
+	err := downloader.DownloadFile(context.Background(), srv.URL, dst, downloader.WithRetries(1))
+	require.Error(t, err)
+
+	assert.NoFileExists(t, dst)
+	assert.FileExists(t, dst+".part")
+	assert.FileExists(t, dst+".part.json")
+}
+
+func TestDownloadFile_Parallel(t *testing.T) {
+	t.Parallel()
+
+	large := bytes.Repeat([]byte("abcdefghijklmnopqrstuvwxyz"), 200_000) // Just under 5 MiB.
+	sum := sha512.Sum512(large)
+
+	tests := []struct {
+		name       string
+		ranges     bool
+		wantRanged int64
+	}{
+		{name: "splits into ranges", ranges: true, wantRanged: 4},
+		{name: "falls back without range support", ranges: false, wantRanged: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ranged atomic.Int64
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tt.ranges {
+					r.Header.Del("Range")
+				}
+
+				if r.Header.Get("Range") != "" {
+					ranged.Add(1)
+				}
+
+				w.Header().Set("ETag", etag)
+				http.ServeContent(w, r, "file", time.Unix(0, 0), bytes.NewReader(large))
+			}))
+			t.Cleanup(srv.Close)
+
+			dst := filepath.Join(t.TempDir(), "file.zip")
+			tracker := &lastUpdate{}
+
+			require.NoError(t, downloader.DownloadFile(context.Background(), srv.URL, dst,
+				downloader.WithConnections(8),
+				downloader.WithProgress(tracker),
+				downloader.WithSHA512(hex.EncodeToString(sum[:]))))
+
+			got, err := os.ReadFile(dst)
+			require.NoError(t, err)
+			assert.Equal(t, large, got)
+			assert.Equal(t, tt.wantRanged, ranged.Load())
+			assert.Equal(t, int64(len(large)), tracker.downloaded.Load())
+		})
+	}
+}
+
+// seedParallel leaves a parallel download behind, with the given ranges of
+// large on disk and the rest of the file zeroed.
+func seedParallel(t *testing.T, dst, url string, large []byte, chunks []map[string]int64) {
+	t.Helper()
+
+	data := make([]byte, len(large))
+
+	for _, c := range chunks {
+		copy(data[c["start"]:c["start"]+c["done"]], large[c["start"]:])
+	}
+
+	require.NoError(t, os.WriteFile(dst+".part", data, 0o644))
+
+	meta, err := json.Marshal(map[string]any{"url": url, "etag": etag, "size": len(large), "chunks": chunks})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst+".part.json", meta, 0o644))
+}
+
+func TestDownloadFile_ResumeParallel(t *testing.T) {
+	t.Parallel()
+
+	large := bytes.Repeat([]byte("abcdefghijklmnopqrstuvwxyz"), 200_000)
+	size := int64(len(large))
+	half := size / 2
+
+	// The first range is complete, the second has a gap after 1 MiB.
+	chunks := []map[string]int64{
+		{"start": 0, "end": half - 1, "done": half},
+		{"start": half, "end": size - 1, "done": 1 << 20},
+	}
+
+	tests := []struct {
+		name        string
+		connections int
+		wantSent    int64
+	}{
+		{name: "in parallel", connections: 4, wantSent: size - half - 1<<20},
+		{name: "over one connection from the first gap", connections: 1, wantSent: size - half - 1<<20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var sent atomic.Int64
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", etag)
+				http.ServeContent(&countingWriter{ResponseWriter: w, n: &sent}, r, "file", time.Unix(0, 0), bytes.NewReader(large))
+			}))
+			t.Cleanup(srv.Close)
+
+			dst := filepath.Join(t.TempDir(), "file.zip")
+			seedParallel(t, dst, srv.URL, large, chunks)
+
+			require.NoError(t, downloader.DownloadFile(context.Background(), srv.URL, dst, downloader.WithConnections(tt.connections)))
+
+			got, err := os.ReadFile(dst)
+			require.NoError(t, err)
+			assert.Equal(t, large, got)
+			assert.Equal(t, tt.wantSent, sent.Load())
+			assert.NoFileExists(t, dst+".part.json")
+		})
+	}
+}
+
+func TestDownloadFile_ParallelKeepsProgressOnFailure(t *testing.T) {
+	t.Parallel()
+
+	large := bytes.Repeat([]byte("abcdefghijklmnopqrstuvwxyz"), 200_000)
+
+	var failing atomic.Bool
+
+	failing.Store(true)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every range but the first fails.
+		if failing.Load() && r.Header.Get("Range") != "" && !strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "file", time.Unix(0, 0), bytes.NewReader(large))
+	}))
+	t.Cleanup(srv.Close)
+
+	dst := filepath.Join(t.TempDir(), "file.zip")
+	opts := []downloader.Option{downloader.WithConnections(4), downloader.WithRetries(1)}
+
+	require.Error(t, downloader.DownloadFile(context.Background(), srv.URL, dst, opts...))
+
+	var state struct {
+		Chunks []struct{ Start, End, Done int64 }
+	}
+
+	meta, err := os.ReadFile(dst + ".part.json")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(meta, &state))
+	require.Len(t, state.Chunks, 4)
+
+	failing.Store(false)
+
+	require.NoError(t, downloader.DownloadFile(context.Background(), srv.URL, dst, opts...))
+
+	got, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, large, got)
+}
+
+func TestDownloadFile_ChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	var sent atomic.Int64
+
+	srv := newServer(t, true, &sent)
+	dst := filepath.Join(t.TempDir(), "file.zip")
+
+	err := downloader.DownloadFile(context.Background(), srv.URL, dst, downloader.WithSHA512(strings.Repeat("0", 128)))
+	require.ErrorIs(t, err, downloader.ErrChecksumMismatch)
+
+	assert.NoFileExists(t, dst)
+	assert.NoFileExists(t, dst+".part")
+}
+
+// lastUpdate records the most recent progress reported.
+type lastUpdate struct {
+	downloaded atomic.Int64
+}
+
+func (u *lastUpdate) Update(downloaded int64, _ int64) {
+	u.downloaded.Store(downloaded)
+}
+
+func TestSimpleTracker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("prints completion once", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+
+		tracker := downloader.NewSimpleTracker(&out, "Godot templates")
+		tracker.Update(0, 2048)
+		tracker.Update(2048, 2048)
+		tracker.Update(2048, 2048)
+
+		assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+		assert.Contains(t, out.String(), "Godot templates: 100%")
+	})
+
+	t.Run("copes with unknown length", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+
+		tracker := downloader.NewSimpleTracker(&out, "Godot templates").WithInterval(0)
+		tracker.Update(0, -1)
+		tracker.Update(2<<20, -1)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.Regexp(t, `^Godot templates: 2\.0 MB \(\d+\.\d MB/s\)$`, lines[1])
+
+		tracker.Finish()
+		tracker.Finish()
+
+		lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.Regexp(t, `^Godot templates: 100% \(2\.0 MB\) in \d+s$`, lines[2])
+	})
+
+	t.Run("finishes downloads of unknown length", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			// Flushing before writing leaves out the Content-Length.
+			w.(http.Flusher).Flush()
+			_, _ = w.Write(content)
+		}))
+		t.Cleanup(srv.Close)
+
+		var out bytes.Buffer
+
+		dst := filepath.Join(t.TempDir(), "file.zip")
+		tracker := downloader.NewSimpleTracker(&out, "Godot templates")
+
+		require.NoError(t, downloader.DownloadFile(context.Background(), srv.URL, dst, downloader.WithProgress(tracker)))
+
+		assert.Regexp(t, `^Godot templates: 100% \(0\.1 MB\) in \d+s\n$`, out.String())
+	})
+}
@@ -0,0 +1,380 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// minChunkSize is the smallest range worth a connection of its own.
+const minChunkSize = 1 << 20
+
+// errRangesUnsupported means the file has to be fetched in a single stream.
+var errRangesUnsupported = errors.New("server does not support range requests")
+
+// stateInterval is how often the progress of a parallel download is saved,
+// so that it can be resumed after the process is killed.
+const stateInterval = time.Second
+
+// chunk is a byte range of the remote file, fetched over its own connection.
+type chunk struct {
+	start int64
+	end   int64 // Inclusive, as in a Range header.
+	done  atomic.Int64
+}
+
+// complete reports whether the whole range is on disk.
+func (c *chunk) complete() bool {
+	return c.done.Load() >= c.end-c.start+1
+}
+
+// splitChunks divides size bytes into at most n ranges of at least
+// minChunkSize bytes each.
+func splitChunks(size int64, n int) []*chunk {
+	n = int(min(int64(n), size/minChunkSize))
+	if n < 1 {
+		n = 1
+	}
+
+	chunks := make([]*chunk, 0, n)
+	step := size / int64(n)
+
+	for i := range int64(n) {
+		end := (i+1)*step - 1
+		if i == int64(n)-1 {
+			end = size - 1
+		}
+
+		chunks = append(chunks, &chunk{start: i * step, end: end})
+	}
+
+	return chunks
+}
+
+// snapshot returns the state of a parallel download with the progress of its
+// chunks.
+func snapshot(state *partialState, chunks []*chunk) *partialState {
+	s := *state
+	s.Chunks = lo.Map(chunks, func(c *chunk, _ int) chunkState {
+		return chunkState{Start: c.start, End: c.end, Done: c.done.Load()}
+	})
+
+	return &s
+}
+
+// resumeChunks returns the chunks of an earlier parallel download of the same
+// file, or nil if there is none.
+func resumeChunks(part *partialFile, url string, probed *partialState, size int64) []*chunk {
+	_, prev := part.resumeState(url)
+	if !prev.parallel() || prev.Size != size || !prev.sameFile(probed) {
+		return nil
+	}
+
+	return lo.Map(prev.Chunks, func(c chunkState, _ int) *chunk {
+		resumed := &chunk{start: c.Start, end: c.End}
+		resumed.done.Store(c.Done)
+
+		return resumed
+	})
+}
+
+// downloadParallel fetches the file in ranges over several connections. It
+// returns errRangesUnsupported if the server cannot serve the file that way,
+// in which case the caller falls back to a single stream.
+//
+// The ranges are written straight into a preallocated partial file, and the
+// progress of each range is saved with it. A failed or cancelled download is
+// resumed from there by the next call.
+func downloadParallel(ctx context.Context, url string, part *partialFile, opts *downloadOptions) error {
+	state, size, err := probeRanges(ctx, url, opts)
+	if err != nil {
+		slog.Debug("Downloading over a single connection", "url", url, "reason", err)
+
+		return errRangesUnsupported
+	}
+
+	state.Size = size
+
+	chunks := resumeChunks(part, url, state, size)
+	if chunks != nil {
+		slog.Debug("Resuming parallel download", "url", url, "offset", lo.SumBy(chunks, func(c *chunk) int64 { return c.done.Load() }))
+	} else {
+		if chunks = splitChunks(size, opts.connections); len(chunks) < 2 { //nolint:mnd
+			return errRangesUnsupported
+		}
+
+		if err := part.allocate(size); err != nil {
+			return err
+		}
+	}
+
+	if err := part.save(snapshot(state, chunks)); err != nil {
+		return err
+	}
+
+	slog.Debug("Downloading in parallel", "url", url, "size", size, "connections", len(chunks))
+
+	f, err := os.OpenFile(part.path, os.O_WRONLY, defaultFilePermissions)
+	if err != nil {
+		return fmt.Errorf("opening partial file %s: %w", part.path, err)
+	}
+
+	progress := &sharedProgress{progressWriter: progressWriter{
+		downloaded: lo.SumBy(chunks, func(c *chunk) int64 { return c.done.Load() }),
+		total:      size,
+		callback:   opts.progressTracker,
+	}}
+
+	err = fetchChunks(ctx, url, part, state, f, chunks, progress, opts)
+
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("closing partial file %s: %w", part.path, closeErr)
+	}
+
+	if errors.Is(err, errRangesUnsupported) {
+		// The file changed, so the ranges on disk may not belong together.
+		part.discard()
+
+		return err
+	}
+
+	// Whatever happened, record what is on disk so that it can be resumed.
+	if saveErr := part.save(snapshot(state, chunks)); err == nil {
+		err = saveErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return verifyChunks(part, chunks, size)
+}
+
+// verifyChunks checks that a parallel download is complete before it is moved
+// into place.
+func verifyChunks(part *partialFile, chunks []*chunk, size int64) error {
+	if incomplete := lo.CountBy(chunks, func(c *chunk) bool { return !c.complete() }); incomplete > 0 {
+		return fmt.Errorf("downloading content: %w (%d ranges incomplete)", io.ErrUnexpectedEOF, incomplete)
+	}
+
+	info, err := os.Stat(part.path)
+	if err != nil {
+		return fmt.Errorf("checking partial file %s: %w", part.path, err)
+	}
+
+	if info.Size() != size {
+		part.discard()
+
+		return fmt.Errorf("downloading content: partial file is %d bytes, expected %d", info.Size(), size)
+	}
+
+	return nil
+}
+
+// fetchChunks downloads the incomplete chunks concurrently, saving their
+// progress as they go. The first failure cancels the remaining chunks.
+func fetchChunks(ctx context.Context, url string, part *partialFile, state *partialState, f *os.File, chunks []*chunk, progress *sharedProgress, opts *downloadOptions) error {
+	chunkCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+
+	for _, c := range chunks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := c.fetch(chunkCtx, url, state, f, progress, opts); err != nil {
+				cancel(err)
+			}
+		}()
+	}
+
+	stop, stopped := make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(stateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := part.save(snapshot(state, chunks)); err != nil {
+					slog.Debug("Failed to save download progress", "error", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(stop)
+	<-stopped
+
+	if err := ctx.Err(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if err := context.Cause(chunkCtx); err != nil && !errors.Is(err, context.Canceled) {
+		return err //nolint:wrapcheck
+	}
+
+	progress.flush()
+
+	return nil
+}
+
+// fetch downloads the rest of a chunk, retrying transient errors from where
+// the previous attempt stopped.
+func (c *chunk) fetch(ctx context.Context, url string, state *partialState, f *os.File, progress *sharedProgress, opts *downloadOptions) error {
+	if c.complete() {
+		return nil
+	}
+
+	var err error
+
+	for attempt := range opts.retries {
+		if err = c.attempt(ctx, url, state, f, progress, opts); err == nil || errors.Is(err, errRangesUnsupported) {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err() //nolint:wrapcheck
+		}
+
+		if attempt < opts.retries-1 {
+			if err := backoff(ctx, attempt); err != nil {
+				return err
+			}
+		}
+	}
+
+	return fmt.Errorf("failed to download bytes %d-%d of %s after %d attempts: %w", c.start, c.end, url, opts.retries, err)
+}
+
+func (c *chunk) attempt(ctx context.Context, url string, state *partialState, f *os.File, progress *sharedProgress, opts *downloadOptions) error {
+	offset := c.start + c.done.Load()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating HTTP request: %w", err)
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, c.end))
+	req.Header.Set("If-Range", state.validator())
+
+	res, err := opts.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("performing HTTP request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusPartialContent {
+		if res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices {
+			// The server ignored the range, or the file changed since it was
+			// probed. Either way the ranges cannot be assembled into one file.
+			return errRangesUnsupported
+		}
+
+		return fmt.Errorf("received non-success HTTP status code %d: %s", res.StatusCode, res.Status)
+	}
+
+	start, _, err := parseContentRange(res.Header.Get("Content-Range"))
+	if err != nil || start != offset || !state.matches(res.Header) {
+		return errRangesUnsupported
+	}
+
+	remaining := c.end + 1 - offset
+	writer := &chunkWriter{w: io.NewOffsetWriter(f, offset), c: c}
+	reader := io.TeeReader(io.LimitReader(newContextReader(ctx, res.Body), remaining), progress)
+
+	n, err := io.CopyBuffer(writer, reader, make([]byte, opts.bufferSize))
+
+	if err != nil {
+		return fmt.Errorf("downloading content: %w", err)
+	}
+
+	if n != remaining {
+		return fmt.Errorf("downloading content: %w (%d of %d bytes)", io.ErrUnexpectedEOF, n, remaining)
+	}
+
+	return nil
+}
+
+// chunkWriter counts the bytes of a chunk as they are written, so that saved
+// progress never includes data that isn't on disk.
+type chunkWriter struct {
+	w io.Writer
+	c *chunk
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.c.done.Add(int64(n))
+
+	return n, err //nolint:wrapcheck
+}
+
+// probeRanges checks that the server serves the file in ranges, returning its
+// size and the validators that make sure every range comes from the same file.
+func probeRanges(ctx context.Context, url string, opts *downloadOptions) (*partialState, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("creating HTTP request: %w", err)
+	}
+
+	res, err := opts.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("performing HTTP request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("received HTTP status code %d: %s", res.StatusCode, res.Status)
+	}
+
+	if res.Header.Get("Accept-Ranges") != "bytes" || res.ContentLength <= 0 {
+		return nil, 0, errRangesUnsupported
+	}
+
+	// Ranges without a validator could silently mix two versions of the file.
+	state := newPartialState(url, res.Header)
+	if !state.resumable() {
+		return nil, 0, errRangesUnsupported
+	}
+
+	return state, res.ContentLength, nil
+}
+
+// sharedProgress reports the combined progress of concurrent writers.
+type sharedProgress struct {
+	mu sync.Mutex
+	progressWriter
+}
+
+func (p *sharedProgress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.progressWriter.Write(b)
+}
+
+func (p *sharedProgress) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.progressWriter.flush()
+}
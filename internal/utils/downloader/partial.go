@@ -0,0 +1,318 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	partialSuffix     = ".part"
+	partialMetaSuffix = ".part.json"
+)
+
+// partialState is persisted next to a partial file and describes the remote
+// resource it was downloaded from, so that a resumed download can be checked
+// against the same version of the file.
+type partialState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	// Size and Chunks are set for a parallel download, whose partial file is
+	// preallocated and filled in by ranges. Chunks records how much of each
+	// range is on disk.
+	Size   int64        `json:"size,omitempty"`
+	Chunks []chunkState `json:"chunks,omitempty"`
+}
+
+// chunkState is the persisted progress of a range of a parallel download.
+type chunkState struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  int64 `json:"done"`
+}
+
+func newPartialState(url string, header http.Header) *partialState {
+	return &partialState{
+		URL:          url,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+}
+
+// validator returns the value to use in an If-Range header. Weak entity tags
+// are not allowed in If-Range, in which case the modification time is used.
+func (s *partialState) validator() string {
+	if s.ETag != "" && !strings.HasPrefix(s.ETag, "W/") {
+		return s.ETag
+	}
+
+	return s.LastModified
+}
+
+// resumable reports whether there is enough information to safely resume.
+func (s *partialState) resumable() bool {
+	return s.validator() != ""
+}
+
+// parallel reports whether the state belongs to a parallel download.
+func (s *partialState) parallel() bool {
+	return s != nil && len(s.Chunks) > 0
+}
+
+// prefix returns how many bytes from the start of a parallel download are on
+// disk without gaps.
+func (s *partialState) prefix() int64 {
+	var prefix int64
+
+	for _, c := range s.Chunks {
+		if c.Start != prefix {
+			break
+		}
+
+		prefix += c.Done
+
+		if c.Done < c.End-c.Start+1 {
+			break
+		}
+	}
+
+	return prefix
+}
+
+// sameFile reports whether two states describe the same version of a file.
+func (s *partialState) sameFile(other *partialState) bool {
+	return s.ETag == other.ETag && s.LastModified == other.LastModified
+}
+
+// matches checks that a partial response refers to the same remote file.
+func (s *partialState) matches(header http.Header) bool {
+	if etag := header.Get("ETag"); etag != "" && s.ETag != "" {
+		return etag == s.ETag
+	}
+
+	if modified := header.Get("Last-Modified"); modified != "" && s.LastModified != "" {
+		return modified == s.LastModified
+	}
+
+	return true
+}
+
+// partialFile manages the on-disk state of an incomplete download.
+type partialFile struct {
+	path     string
+	metaPath string
+}
+
+// RemovePartial deletes the partial download of dst, if there is one, so that
+// the next download of it starts over.
+func RemovePartial(dst string) error {
+	for _, path := range []string{dst + partialSuffix, dst + partialMetaSuffix} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("removing partial download: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func newPartialFile(dst string) *partialFile {
+	return &partialFile{
+		path:     dst + partialSuffix,
+		metaPath: dst + partialMetaSuffix,
+	}
+}
+
+// resumeState returns the number of bytes that can be resumed from and the
+// state they were downloaded with. A zero offset means starting over. For a
+// parallel download, the offset is the part of the file without gaps.
+func (p *partialFile) resumeState(url string) (int64, *partialState) {
+	info, err := os.Stat(p.path)
+	if err != nil || info.Size() == 0 {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(p.metaPath)
+	if err != nil {
+		return 0, nil
+	}
+
+	var state partialState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, nil
+	}
+
+	if state.URL != url || !state.resumable() {
+		return 0, nil
+	}
+
+	if state.parallel() {
+		if info.Size() != state.Size {
+			return 0, nil
+		}
+
+		return state.prefix(), &state
+	}
+
+	return info.Size(), &state
+}
+
+// reset truncates the partial file and records the state of a new download.
+func (p *partialFile) reset(state *partialState) error {
+	if err := os.WriteFile(p.path, nil, defaultFilePermissions); err != nil {
+		return fmt.Errorf("creating partial file %s: %w", p.path, err)
+	}
+
+	return p.save(state)
+}
+
+// save records the state of the download next to the partial file.
+func (p *partialFile) save(state *partialState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding partial state: %w", err)
+	}
+
+	if err := os.WriteFile(p.metaPath, data, defaultFilePermissions); err != nil {
+		return fmt.Errorf("writing partial state %s: %w", p.metaPath, err)
+	}
+
+	return nil
+}
+
+// linearize turns a parallel download into one that can be resumed over a
+// single connection, keeping the data up to its first gap. Anything else is
+// left alone.
+func (p *partialFile) linearize(url string) error {
+	offset, state := p.resumeState(url)
+	if !state.parallel() {
+		return nil
+	}
+
+	if offset == 0 {
+		p.discard()
+
+		return nil
+	}
+
+	if err := os.Truncate(p.path, offset); err != nil {
+		return fmt.Errorf("truncating partial file %s: %w", p.path, err)
+	}
+
+	slog.Debug("Resuming parallel download over a single connection", "url", url, "offset", offset)
+
+	state.Size, state.Chunks = 0, nil
+
+	return p.save(state)
+}
+
+// allocate creates a partial file of the given size to be filled in out of
+// order. Its state must be saved with the progress of each range, as the file
+// has holes until it is done.
+func (p *partialFile) allocate(size int64) error {
+	if err := os.WriteFile(p.path, nil, defaultFilePermissions); err != nil {
+		return fmt.Errorf("creating partial file %s: %w", p.path, err)
+	}
+
+	if err := os.Truncate(p.path, size); err != nil {
+		return fmt.Errorf("allocating partial file %s: %w", p.path, err)
+	}
+
+	return nil
+}
+
+// discard removes the partial file and its metadata.
+func (p *partialFile) discard() {
+	_ = os.Remove(p.path)
+	_ = os.Remove(p.metaPath)
+}
+
+// write appends the body to the partial file, which must be offset bytes long.
+func (p *partialFile) write(ctx context.Context, body io.Reader, offset int64, total int64, opts *downloadOptions) error {
+	f, err := os.OpenFile(p.path, os.O_WRONLY|os.O_APPEND, defaultFilePermissions)
+	if err != nil {
+		return fmt.Errorf("opening partial file %s: %w", p.path, err)
+	}
+	defer f.Close()
+
+	// Wrap the response body with a context-aware reader
+	bodyReader := newContextReader(ctx, body)
+
+	// Create a progress writer
+	progress := &progressWriter{
+		downloaded: offset,
+		total:      total, // Can be -1 if the length is unknown.
+		callback:   opts.progressTracker,
+	}
+
+	// Create a TeeReader to track progress
+	reader := io.TeeReader(bodyReader, progress)
+
+	// Copy the data to the partial file
+	if _, err := io.CopyBuffer(f, reader, make([]byte, opts.bufferSize)); err != nil {
+		return fmt.Errorf("downloading content: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing partial file %s: %w", p.path, err)
+	}
+
+	if total >= 0 && progress.downloaded != total {
+		return fmt.Errorf("downloading content: %w (%d of %d bytes)", io.ErrUnexpectedEOF, progress.downloaded, total)
+	}
+
+	progress.flush()
+
+	return nil
+}
+
+var errInvalidContentRange = errors.New("invalid Content-Range header")
+
+// parseContentRange parses a "bytes start-end/total" or "bytes */total"
+// header. The total is -1 when the server doesn't know the full length.
+func parseContentRange(value string) (int64, int64, error) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, errInvalidContentRange
+	}
+
+	span, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, errInvalidContentRange
+	}
+
+	total := int64(-1)
+
+	if size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			return 0, 0, errInvalidContentRange
+		}
+
+		total = n
+	}
+
+	if span == "*" {
+		return 0, total, nil
+	}
+
+	first, _, ok := strings.Cut(span, "-")
+	if !ok {
+		return 0, 0, errInvalidContentRange
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, errInvalidContentRange
+	}
+
+	return start, total, nil
+}
@@ -0,0 +1,270 @@
+package filelock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultPollInterval      = 500 * time.Millisecond
+	defaultHeartbeatInterval = 10 * time.Second
+	defaultStaleAfter        = 1 * time.Minute
+)
+
+// ErrLocked is returned by TryAcquire when the lock is held by someone else.
+var ErrLocked = errors.New("lock is held by another process")
+
+// owner is written into the lock file to identify the holder.
+type owner struct {
+	PID        int       `json:"pid"`
+	Hostname   string    `json:"hostname"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+}
+
+// Option defines a function type for setting lock options.
+type Option func(*lockOptions)
+
+type lockOptions struct {
+	pollInterval      time.Duration
+	heartbeatInterval time.Duration
+	staleAfter        time.Duration
+	onWait            func(holder string)
+}
+
+func defaultLockOptions() *lockOptions {
+	return &lockOptions{
+		pollInterval:      defaultPollInterval,
+		heartbeatInterval: defaultHeartbeatInterval,
+		staleAfter:        defaultStaleAfter,
+		onWait:            nil,
+	}
+}
+
+// WithPollInterval sets how often a waiting process checks the lock.
+func WithPollInterval(d time.Duration) Option {
+	return func(o *lockOptions) {
+		o.pollInterval = d
+	}
+}
+
+// WithStaleAfter sets how long a lock may go without a heartbeat before it is
+// considered abandoned. The heartbeat interval is derived from it.
+func WithStaleAfter(d time.Duration) Option {
+	return func(o *lockOptions) {
+		o.staleAfter = d
+		o.heartbeatInterval = d / 6 //nolint:mnd
+	}
+}
+
+// WithOnWait registers a callback invoked once if the lock is busy.
+func WithOnWait(fn func(holder string)) Option {
+	return func(o *lockOptions) {
+		o.onWait = fn
+	}
+}
+
+// Lock is an advisory lock backed by a file, shared between processes.
+//
+// The holder refreshes the modification time of the lock file periodically.
+// A lock is considered stale, and is taken over, when its holder is a process
+// on this host that no longer exists, or when the heartbeat stops.
+type Lock struct {
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Acquire blocks until the lock at path is obtained or ctx is cancelled.
+func Acquire(ctx context.Context, path string, opt ...Option) (*Lock, error) {
+	opts := defaultLockOptions()
+	for _, o := range opt {
+		o(opts)
+	}
+
+	waiting := false
+
+	for {
+		lock, err := tryAcquire(path, opts)
+		if err == nil {
+			return lock, nil
+		}
+
+		if !errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+
+		if !waiting && opts.onWait != nil {
+			opts.onWait(describeHolder(path))
+		}
+
+		waiting = true
+
+		select {
+		case <-time.After(opts.pollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err() //nolint:wrapcheck
+		}
+	}
+}
+
+// TryAcquire obtains the lock at path without waiting. It returns ErrLocked
+// if the lock is currently held.
+func TryAcquire(path string, opt ...Option) (*Lock, error) {
+	opts := defaultLockOptions()
+	for _, o := range opt {
+		o(opts)
+	}
+
+	return tryAcquire(path, opts)
+}
+
+func tryAcquire(path string, opts *lockOptions) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	if err := create(path); err != nil {
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		if !removeIfStale(path, opts.staleAfter) {
+			return nil, ErrLocked
+		}
+
+		// The stale lock is gone, race the other waiters for it.
+		if err := create(path); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return nil, ErrLocked
+			}
+
+			return nil, err
+		}
+	}
+
+	lock := &Lock{
+		path: path,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go lock.heartbeat(opts.heartbeatInterval)
+
+	return lock, nil
+}
+
+// Release gives up the lock.
+func (l *Lock) Release() error {
+	close(l.stop)
+	<-l.done
+
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to release lock %s: %w", l.path, err)
+	}
+
+	return nil
+}
+
+func (l *Lock) heartbeat(interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if err := os.Chtimes(l.path, now, now); err != nil {
+				slog.Warn("Failed to refresh lock", "path", l.path, "error", err)
+			}
+		}
+	}
+}
+
+// create atomically creates the lock file, failing if it already exists.
+func create(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o0644)
+	if err != nil {
+		return fmt.Errorf("failed to create lock %s: %w", path, err)
+	}
+	defer f.Close()
+
+	hostname, _ := os.Hostname()
+
+	if err := json.NewEncoder(f).Encode(owner{PID: os.Getpid(), Hostname: hostname, AcquiredAt: time.Now().UTC()}); err != nil {
+		return fmt.Errorf("failed to write lock %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// removeIfStale removes the lock at path if its holder is gone, reporting
+// whether the lock no longer exists.
+func removeIfStale(path string, staleAfter time.Duration) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Is(err, fs.ErrNotExist)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Is(err, fs.ErrNotExist)
+	}
+
+	if !isStale(data, info.ModTime(), staleAfter) {
+		return false
+	}
+
+	// Make sure the lock wasn't replaced since we looked at it.
+	if current, err := os.ReadFile(path); err != nil || string(current) != string(data) {
+		return errors.Is(err, fs.ErrNotExist)
+	}
+
+	slog.Warn("Removing stale lock", "path", path, "holder", string(data))
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+
+	return true
+}
+
+func isStale(data []byte, modified time.Time, staleAfter time.Duration) bool {
+	if time.Since(modified) > staleAfter {
+		return true
+	}
+
+	var o owner
+	if err := json.Unmarshal(data, &o); err != nil {
+		// Possibly still being written, give the holder until the timeout.
+		return false
+	}
+
+	hostname, _ := os.Hostname()
+
+	return o.Hostname == hostname && o.PID > 0 && !processAlive(o.PID)
+}
+
+func describeHolder(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "unknown"
+	}
+
+	var o owner
+	if err := json.Unmarshal(data, &o); err != nil {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("pid %d on %s since %s", o.PID, o.Hostname, o.AcquiredAt.Local().Format(time.TimeOnly))
+}
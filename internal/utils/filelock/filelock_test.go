@@ -0,0 +1,104 @@
+package filelock_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ruffel/godotreleaser/internal/utils/filelock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire_WaitsForRelease(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "4.3.lock")
+
+	first, err := filelock.Acquire(context.Background(), path)
+	require.NoError(t, err)
+
+	_, err = filelock.TryAcquire(path)
+	require.ErrorIs(t, err, filelock.ErrLocked)
+
+	acquired := make(chan *filelock.Lock)
+
+	go func() {
+		second, err := filelock.Acquire(context.Background(), path, filelock.WithPollInterval(10*time.Millisecond))
+		assert.NoError(t, err)
+
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while still held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, first.Release())
+
+	select {
+	case second := <-acquired:
+		require.NoError(t, second.Release())
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock not acquired after release")
+	}
+
+	assert.NoFileExists(t, path)
+}
+
+func TestAcquire_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "4.3.lock")
+
+	lock, err := filelock.Acquire(context.Background(), path)
+	require.NoError(t, err)
+
+	defer lock.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = filelock.Acquire(ctx, path, filelock.WithPollInterval(10*time.Millisecond))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAcquire_StaleLocks(t *testing.T) {
+	t.Parallel()
+
+	hostname, _ := os.Hostname()
+
+	tests := []struct {
+		name     string
+		pid      int
+		hostname string
+		age      time.Duration
+	}{
+		{name: "crashed holder on this host", pid: 1 << 30, hostname: hostname},
+		{name: "missed heartbeat", pid: os.Getpid(), hostname: "elsewhere", age: 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "4.3.lock")
+
+			data, err := json.Marshal(map[string]any{"pid": tt.pid, "hostname": tt.hostname})
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(path, data, 0o644))
+
+			modified := time.Now().Add(-tt.age)
+			require.NoError(t, os.Chtimes(path, modified, modified))
+
+			lock, err := filelock.TryAcquire(path)
+			require.NoError(t, err)
+			require.NoError(t, lock.Release())
+		})
+	}
+}
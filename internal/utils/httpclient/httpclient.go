@@ -0,0 +1,223 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Environment variables that complement the command line flags. Credentials
+// are better passed this way, as flags are visible in the process list.
+const (
+	EnvProxy    = "GODOTRELEASER_PROXY"
+	EnvCABundle = "GODOTRELEASER_CA_BUNDLE"
+	EnvHeaders  = "GODOTRELEASER_HEADERS" // Newline separated "Name: value" pairs.
+	EnvAuth     = "GODOTRELEASER_AUTH"    // Newline separated "host=scheme:credentials" pairs.
+)
+
+// Config describes how to reach download servers.
+type Config struct {
+	// Proxy is the URL of the proxy to use for all requests. When empty, the
+	// standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables are honoured.
+	Proxy string
+	// CABundle is a PEM file of extra root certificates to trust.
+	CABundle string
+	// Headers are added to every request, in the form "Name: value".
+	Headers []string
+	// Auth holds credentials per host, in the form "host=bearer:TOKEN" or
+	// "host=basic:USER:PASSWORD".
+	Auth []string
+}
+
+// AddFlags registers the command line flags for the configuration.
+func (c *Config) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&c.Proxy, "proxy", "", "Proxy URL for downloads (or set $"+EnvProxy+")")
+	flags.StringVar(&c.CABundle, "ca-bundle", "", "PEM file with extra CA certificates to trust for downloads (or set $"+EnvCABundle+")")
+	flags.StringArrayVar(&c.Headers, "header", nil, `Extra request header for downloads, "Name: value" (repeatable, or set $`+EnvHeaders+")")
+	flags.StringArrayVar(&c.Auth, "auth", nil, `Credentials for a download host, "host=bearer:TOKEN" or "host=basic:USER:PASS" (repeatable, or set $`+EnvAuth+")")
+}
+
+// withEnvironment returns the configuration with the environment variables
+// applied. Flags take precedence over the environment for single values.
+func (c *Config) withEnvironment() *Config {
+	merged := *c
+
+	if merged.Proxy == "" {
+		merged.Proxy = os.Getenv(EnvProxy)
+	}
+
+	if merged.CABundle == "" {
+		merged.CABundle = os.Getenv(EnvCABundle)
+	}
+
+	merged.Headers = append(splitLines(os.Getenv(EnvHeaders)), c.Headers...)
+	merged.Auth = append(splitLines(os.Getenv(EnvAuth)), c.Auth...)
+
+	return &merged
+}
+
+// Client builds an HTTP client from the configuration and environment.
+func (c *Config) Client() (*http.Client, error) {
+	cfg := c.withEnvironment()
+
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("unexpected default HTTP transport")
+	}
+
+	transport := base.Clone()
+
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", cfg.Proxy, err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if cfg.CABundle != "" {
+		pool, err := loadCertPool(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	headers, err := parseHeaders(cfg.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := parseAuth(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: &headerTransport{base: transport, headers: headers, auth: auth},
+	}, nil
+}
+
+// headerTransport adds the configured headers and per host credentials to
+// every request, including those made when following redirects.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+	auth    map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.headers) == 0 && len(t.auth) == 0 {
+		return t.base.RoundTrip(req) //nolint:wrapcheck
+	}
+
+	req = req.Clone(req.Context())
+
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+
+	if credentials, ok := t.auth[strings.ToLower(req.URL.Hostname())]; ok {
+		req.Header.Set("Authorization", credentials)
+	}
+
+	return t.base.RoundTrip(req) //nolint:wrapcheck
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+
+	return pool, nil
+}
+
+func parseHeaders(values []string) (http.Header, error) {
+	headers := make(http.Header)
+
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf(`invalid header %q, expected "Name: value"`, v)
+		}
+
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	return headers, nil
+}
+
+// authUsage describes the form of an auth entry.
+const authUsage = `"host=bearer:token" or "host=basic:user:password"`
+
+// parseAuth turns "host=scheme:credentials" entries into Authorization header
+// values keyed by lower case host name.
+func parseAuth(values []string) (map[string]string, error) {
+	auth := make(map[string]string)
+
+	for _, v := range values {
+		host, spec, ok := strings.Cut(v, "=")
+		if !ok || host == "" {
+			return nil, fmt.Errorf(`invalid auth %q, expected "host=scheme:credentials"`, redact(v))
+		}
+
+		// Without a scheme the whole spec may be a token, so neither is ever
+		// included in errors.
+		scheme, credentials, ok := strings.Cut(spec, ":")
+		if !ok || credentials == "" {
+			return nil, fmt.Errorf("invalid auth %s, expected %s", redact(v), authUsage)
+		}
+
+		switch strings.ToLower(scheme) {
+		case "bearer":
+			auth[strings.ToLower(host)] = "Bearer " + credentials
+		case "basic":
+			auth[strings.ToLower(host)] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+		default:
+			return nil, fmt.Errorf("invalid auth %s, expected %s", redact(v), authUsage)
+		}
+	}
+
+	return auth, nil
+}
+
+// redact hides the credentials of an auth entry for use in error messages.
+func redact(v string) string {
+	if host, _, ok := strings.Cut(v, "="); ok {
+		return host + "=***"
+	}
+
+	return "***"
+}
+
+func splitLines(v string) []string {
+	var lines []string
+
+	for _, line := range strings.Split(v, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
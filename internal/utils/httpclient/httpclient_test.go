@@ -0,0 +1,131 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, client *http.Client, address string) string {
+	t.Helper()
+
+	resp, err := client.Get(address) //nolint:noctx
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestClient_HeadersAndAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get("X-Mirror")+"|"+r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	host := lo.Must(url.Parse(srv.URL)).Hostname()
+
+	t.Setenv(EnvAuth, "")
+	t.Setenv(EnvHeaders, "X-Mirror: internal")
+
+	cfg := &Config{Auth: []string{host + "=bearer:secret", "other.example=basic:user:pass"}}
+
+	client, err := cfg.Client()
+	require.NoError(t, err)
+
+	assert.Equal(t, "internal|Bearer secret", get(t, client, srv.URL))
+
+	cfg = &Config{Auth: []string{host + "=basic:user:pass"}}
+
+	client, err = cfg.Client()
+	require.NoError(t, err)
+
+	assert.Equal(t, "internal|Basic dXNlcjpwYXNz", get(t, client, srv.URL))
+}
+
+func TestClient_AuthNotSentToOtherHosts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	t.Setenv(EnvAuth, "")
+	t.Setenv(EnvHeaders, "")
+
+	client, err := (&Config{Auth: []string{"mirror.example=bearer:secret"}}).Client()
+	require.NoError(t, err)
+
+	assert.Empty(t, get(t, client, srv.URL))
+}
+
+func TestClient_CABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	t.Setenv(EnvCABundle, "")
+
+	client, err := (&Config{}).Client()
+	require.NoError(t, err)
+
+	_, err = client.Get(srv.URL) //nolint:noctx,bodyclose
+	require.Error(t, err, "the test server certificate should not be trusted by default")
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600))
+
+	client, err = (&Config{CABundle: bundle}).Client()
+	require.NoError(t, err)
+
+	assert.Equal(t, "ok", get(t, client, srv.URL))
+}
+
+func TestClient_Proxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "proxied "+r.URL.String())
+	}))
+	defer proxy.Close()
+
+	t.Setenv(EnvProxy, proxy.URL)
+
+	client, err := (&Config{}).Client()
+	require.NoError(t, err)
+
+	assert.Equal(t, "proxied http://godot.example/file.zip", get(t, client, "http://godot.example/file.zip"))
+}
+
+func TestClient_InvalidConfig(t *testing.T) {
+	t.Setenv(EnvAuth, "")
+	t.Setenv(EnvHeaders, "")
+
+	tests := map[string]*Config{
+		"header without colon": {Headers: []string{"X-Mirror"}},
+		"auth without host":    {Auth: []string{"bearer:secret"}},
+		"unknown auth scheme":  {Auth: []string{"mirror.example=digest:secret"}},
+		"auth without scheme":  {Auth: []string{"mirror.example=ghp_secret"}},
+		"empty bearer token":   {Auth: []string{"mirror.example=bearer:"}},
+		"empty basic auth":     {Auth: []string{"mirror.example=basic:"}},
+		"missing CA bundle":    {CABundle: filepath.Join(t.TempDir(), "missing.pem")},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := cfg.Client()
+			require.Error(t, err)
+			assert.NotContains(t, err.Error(), "secret")
+		})
+	}
+}
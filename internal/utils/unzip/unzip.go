@@ -2,17 +2,28 @@ package unzip
 
 import (
 	"archive/zip"
+	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
+// Filter selects which files to extract, by their path with the first
+// directory level removed. A nil Filter extracts everything.
+type Filter func(name string) bool
+
 // Extract removes the first directory level and extracts the zip archive to the specified destination directory.
+func Extract(src string, dst string) error {
+	return extract(context.Background(), src, dst, nil)
+}
+
+// extract implements Extract, stopping between files if ctx is cancelled.
 //
 //nolint:cyclop,funlen
-func Extract(src string, dst string) error {
+func extract(ctx context.Context, src string, dst string, filter Filter) error {
 	// Create the destination directory if it doesn't exist
 	if err := os.MkdirAll(dst, 0o0755); err != nil {
 		return fmt.Errorf("failed to create dst: %w", err)
@@ -27,10 +38,15 @@ func Extract(src string, dst string) error {
 
 	// Extract the contents of the archive
 	for _, f := range r.File {
+		if err := ctx.Err(); err != nil {
+			return err //nolint:wrapcheck
+		}
+
 		// Remove the first directory level
-		relativePath := f.Name
-		if idx := strings.Index(f.Name, "/"); idx != -1 {
-			relativePath = f.Name[idx+1:]
+		relativePath := stripFirstLevel(f.Name)
+
+		if filter != nil && !f.FileInfo().IsDir() && !filter(relativePath) {
+			continue
 		}
 
 		fpath := filepath.Join(dst, relativePath) //nolint:gosec
@@ -80,3 +96,155 @@ func Extract(src string, dst string) error {
 
 	return nil
 }
+
+// ReadFile returns the contents of a single file from the zip archive. As with
+// Extract, the first directory level of the archive is ignored.
+func ReadFile(src string, name string) ([]byte, error) {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if stripFirstLevel(f.Name) != name {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("%s not found in %s: %w", name, src, os.ErrNotExist)
+}
+
+// Names returns the names of all entries in the zip archive.
+func Names(src string) ([]string, error) {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	defer r.Close()
+
+	names := make([]string, 0, len(r.File))
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+
+	return names, nil
+}
+
+// stripFirstLevel removes the first directory level from an archive path.
+func stripFirstLevel(name string) string {
+	if idx := strings.Index(name, "/"); idx != -1 {
+		return name[idx+1:]
+	}
+
+	return name
+}
+
+// ExtractAtomic extracts the zip archive like Extract, but into a staging
+// directory next to dst which is only renamed into place once extraction has
+// completed. An existing dst is replaced, so an interrupted extraction never
+// leaves a partially populated dst behind. Only files accepted by filter are
+// extracted.
+//
+// Extraction stops early, removing the staging directory, if ctx is cancelled.
+func ExtractAtomic(ctx context.Context, src string, dst string, filter Filter) error {
+	staging, err := extractStaging(ctx, src, dst, filter)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	if err := os.RemoveAll(dst); err != nil {
+		return fmt.Errorf("failed to remove existing dst: %w", err)
+	}
+
+	if err := os.Rename(staging, dst); err != nil {
+		return fmt.Errorf("failed to move staging directory into place: %w", err)
+	}
+
+	return nil
+}
+
+// ExtractMerge extracts the files accepted by filter into an existing dst,
+// leaving its other files untouched. Like ExtractAtomic, the files are first
+// extracted to a staging directory, and each one is then renamed into place.
+func ExtractMerge(ctx context.Context, src string, dst string, filter Filter) error {
+	staging, err := extractStaging(ctx, src, dst, filter)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	return filepath.WalkDir(staging, func(path string, d fs.DirEntry, err error) error { //nolint:wrapcheck
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(staging, path)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		target := filepath.Join(dst, rel)
+
+		if err := os.MkdirAll(filepath.Dir(target), 0o0755); err != nil {
+			return fmt.Errorf("failed to create directory for file: %w", err)
+		}
+
+		if err := os.Rename(path, target); err != nil {
+			return fmt.Errorf("failed to move %s into place: %w", rel, err)
+		}
+
+		return nil
+	})
+}
+
+// extractStaging extracts the archive into a new staging directory next to
+// dst and returns its path. The caller is responsible for removing it.
+func extractStaging(ctx context.Context, src string, dst string, filter Filter) (string, error) {
+	parent := filepath.Dir(dst)
+	prefix := "." + filepath.Base(dst) + ".staging-"
+
+	if err := os.MkdirAll(parent, 0o0755); err != nil {
+		return "", fmt.Errorf("failed to create parent of dst: %w", err)
+	}
+
+	// Clear out staging directories left behind by crashed extractions.
+	if leftovers, err := filepath.Glob(filepath.Join(parent, prefix+"*")); err == nil {
+		for _, dir := range leftovers {
+			_ = os.RemoveAll(dir)
+		}
+	}
+
+	staging, err := os.MkdirTemp(parent, prefix)
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	if err := os.Chmod(staging, 0o0755); err != nil { //nolint:gosec
+		os.RemoveAll(staging)
+
+		return "", fmt.Errorf("failed to set staging directory permissions: %w", err)
+	}
+
+	if err := extract(ctx, src, staging, filter); err != nil {
+		os.RemoveAll(staging)
+
+		return "", err
+	}
+
+	return staging, nil
+}
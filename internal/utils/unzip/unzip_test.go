@@ -0,0 +1,106 @@
+package unzip_test
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ruffel/godotreleaser/internal/utils/unzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArchive creates a zip archive with the given files below a top-level
+// "templates" directory, like an export templates archive.
+func writeArchive(t *testing.T, files ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "templates.tpz")
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	w := zip.NewWriter(f)
+
+	for _, name := range files {
+		fw, err := w.Create("templates/" + name)
+		require.NoError(t, err)
+
+		_, err = fw.Write([]byte(name))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	return path
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	return names
+}
+
+func TestExtractAtomic(t *testing.T) {
+	t.Parallel()
+
+	archive := writeArchive(t, "version.txt", "linux_release.x86_64", "web_release.zip")
+	dst := filepath.Join(t.TempDir(), "4.3.stable")
+
+	require.NoError(t, os.MkdirAll(dst, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "leftover"), nil, 0o644))
+
+	err := unzip.ExtractAtomic(context.Background(), archive, dst, func(name string) bool {
+		return !strings.HasPrefix(name, "web_")
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"version.txt", "linux_release.x86_64"}, listDir(t, dst))
+	assert.Equal(t, []string{"4.3.stable"}, listDir(t, filepath.Dir(dst)), "no staging directories are left behind")
+}
+
+func TestExtractMerge(t *testing.T) {
+	t.Parallel()
+
+	archive := writeArchive(t, "version.txt", "linux_release.x86_64", "web_release.zip")
+	dst := filepath.Join(t.TempDir(), "4.3.stable")
+
+	require.NoError(t, os.MkdirAll(dst, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "existing"), nil, 0o644))
+
+	err := unzip.ExtractMerge(context.Background(), archive, dst, func(name string) bool {
+		return strings.HasPrefix(name, "web_")
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"existing", "web_release.zip"}, listDir(t, dst))
+	assert.Equal(t, []string{"4.3.stable"}, listDir(t, filepath.Dir(dst)))
+}
+
+func TestExtractAtomic_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	archive := writeArchive(t, "version.txt")
+	dst := filepath.Join(t.TempDir(), "4.3.stable")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := unzip.ExtractAtomic(ctx, archive, dst, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	assert.NoDirExists(t, dst)
+	assert.Empty(t, listDir(t, filepath.Dir(dst)))
+}
@@ -7,17 +7,38 @@ import (
 	"os/exec"
 	"path/filepath"
 
+	"github.com/ruffel/godotreleaser/internal/godot/engine"
 	"github.com/ruffel/godotreleaser/internal/paths"
 )
 
 type Client struct {
 	path string
+	// major is the major Godot version, which decides the command line used.
+	major int
 }
 
-func NewFromPath(path string) (*Client, error) {
-	return &Client{
-		path: path,
-	}, nil
+// Option configures a Client.
+type Option func(*Client)
+
+// WithVersion tells the client which version of Godot the binary is, so that
+// it uses the matching command line. Godot 4 is assumed otherwise.
+func WithVersion(version string) Option {
+	return func(c *Client) {
+		c.major = engine.Major(version)
+	}
+}
+
+func NewFromPath(path string, opts ...Option) (*Client, error) {
+	c := &Client{
+		path:  path,
+		major: engine.Godot4,
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c, nil
 }
 
 func NewFromVersion(version string, mono bool) (*Client, error) {
@@ -26,7 +47,7 @@ func NewFromVersion(version string, mono bool) (*Client, error) {
 		return nil, fmt.Errorf("failed to get binary path: %w", err)
 	}
 
-	return NewFromPath(path)
+	return NewFromPath(path, WithVersion(version))
 }
 
 type BuildOptions struct {
@@ -40,7 +61,14 @@ func (c *Client) Build(ctx context.Context, opts *BuildOptions) error {
 	cleanPreset := filepath.Clean(opts.Preset)
 	cleanPathArg := filepath.Clean(opts.Project)
 
-	cmd := exec.CommandContext(ctx, cleanPath, "--headless", "--quit", opts.ExportType.String(), cleanPreset, cleanPathArg)
+	args := []string{"--headless", "--quit", opts.ExportType.String(), cleanPreset, cleanPathArg}
+
+	// Godot 3 has no --headless, and exports before --quit would take effect.
+	if c.major == engine.Godot3 {
+		args = []string{"--no-window", opts.ExportType.godot3Flag(), cleanPreset, cleanPathArg}
+	}
+
+	cmd := exec.CommandContext(ctx, cleanPath, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -0,0 +1,54 @@
+package client_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ruffel/godotreleaser/pkg/godot/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Build(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the Godot binary")
+	}
+
+	tests := []struct {
+		name    string
+		opts    []client.Option
+		export  client.ExportType
+		wantArg string
+	}{
+		{name: "godot 4", export: client.ExportRelease, wantArg: "--headless --quit --export-release Linux /p/project.godot"},
+		{name: "godot 3", opts: []client.Option{client.WithVersion("3.5.3")}, export: client.ExportRelease, wantArg: "--no-window --export Linux /p/project.godot"},
+		{name: "godot 3 debug", opts: []client.Option{client.WithVersion("3.5.3")}, export: client.ExportDebug, wantArg: "--no-window --export-debug Linux /p/project.godot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			out := filepath.Join(dir, "args")
+			binary := filepath.Join(dir, "godot")
+
+			require.NoError(t, os.WriteFile(binary, []byte("#!/bin/sh\necho \"$@\" > "+out+"\n"), 0o755)) //nolint:gosec
+
+			c, err := client.NewFromPath(binary, tt.opts...)
+			require.NoError(t, err)
+
+			require.NoError(t, c.Build(context.Background(), &client.BuildOptions{Preset: "Linux", Project: "/p/project.godot", ExportType: tt.export}))
+
+			got, err := os.ReadFile(out)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantArg, strings.TrimSpace(string(got)))
+		})
+	}
+}
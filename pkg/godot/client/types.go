@@ -15,6 +15,16 @@ func (t ExportType) String() string {
 	}
 }
 
+// godot3Flag returns the command line flag used by Godot 3, which calls a
+// release export just --export.
+func (t ExportType) godot3Flag() string {
+	if t == ExportRelease {
+		return "--export"
+	}
+
+	return t.String()
+}
+
 const (
 	ExportDebug ExportType = iota
 	ExportRelease
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var versionPattern = regexp.MustCompile(`^(\d+(?:\.\d+)+)\.([a-z_]+\d*)((?:\.[\w-]+)*)$`)
+
+// Info describes a Godot binary, as reported by `godot --version`.
+type Info struct {
+	// Version is the engine version in the form used by the official
+	// downloads, for example "4.3" or "4.2.2".
+	Version string
+	// Status is the release status, for example "stable", "rc1" or, for some
+	// builds from source, "custom_build".
+	Status string
+	// Mono is set for builds with C# support.
+	Mono bool
+	// Raw is the unmodified version string.
+	Raw string
+}
+
+// Info runs the binary to find out which version of Godot it is.
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, filepath.Clean(c.path), "--version")
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to query Godot version: %w", err)
+	}
+
+	return ParseVersion(stdout.String())
+}
+
+// ParseVersion parses the output of `godot --version`, for example
+// "4.3.stable.mono.official.77dcf97d8".
+func ParseVersion(output string) (*Info, error) {
+	// Only the last line holds the version, anything before it is noise such as
+	// driver warnings.
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	raw := strings.TrimSpace(lines[len(lines)-1])
+
+	m := versionPattern.FindStringSubmatch(raw)
+	if m == nil {
+		return nil, fmt.Errorf("unrecognised Godot version %q", raw)
+	}
+
+	return &Info{
+		Version: m[1],
+		Status:  m[2],
+		Mono:    strings.Contains(m[3]+".", ".mono."),
+		Raw:     raw,
+	}, nil
+}
@@ -0,0 +1,70 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/ruffel/godotreleaser/pkg/godot/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		output string
+		want   *client.Info
+	}{
+		{
+			name:   "official",
+			output: "4.3.stable.official.77dcf97d8\n",
+			want:   &client.Info{Version: "4.3", Status: "stable", Raw: "4.3.stable.official.77dcf97d8"},
+		},
+		{
+			name:   "mono",
+			output: "4.2.2.stable.mono.official.15073afe3\n",
+			want:   &client.Info{Version: "4.2.2", Status: "stable", Mono: true, Raw: "4.2.2.stable.mono.official.15073afe3"},
+		},
+		{
+			name:   "custom build",
+			output: "4.4.rc1.custom_build\n",
+			want:   &client.Info{Version: "4.4", Status: "rc1", Raw: "4.4.rc1.custom_build"},
+		},
+		{
+			name:   "custom build without status",
+			output: "4.3.custom_build.77dcf97d8\n",
+			want:   &client.Info{Version: "4.3", Status: "custom_build", Raw: "4.3.custom_build.77dcf97d8"},
+		},
+		{
+			name:   "godot 3",
+			output: "3.5.3.stable.official.6c814135b",
+			want:   &client.Info{Version: "3.5.3", Status: "stable", Raw: "3.5.3.stable.official.6c814135b"},
+		},
+		{
+			name:   "leading noise",
+			output: "WARNING: some driver warning\n4.3.stable.official.77dcf97d8\n",
+			want:   &client.Info{Version: "4.3", Status: "stable", Raw: "4.3.stable.official.77dcf97d8"},
+		},
+		{
+			name:   "garbage",
+			output: "command not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := client.ParseVersion(tt.output)
+			if tt.want == nil {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
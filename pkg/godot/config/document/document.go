@@ -0,0 +1,410 @@
+// Package document edits Godot config files, such as project.godot and
+// export_presets.cfg, without disturbing anything that isn't changed.
+//
+// Unlike the parser package, which decodes a file into a map, a Document keeps
+// the original text of every section, key, comment and blank line, so that
+// writing it back only differs where a value was set or deleted.
+package document
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
+)
+
+// Global is the name of the unnamed section at the top of a file, holding
+// keys such as config_version.
+const Global = ""
+
+// ErrNotFound is returned when a key does not exist.
+var ErrNotFound = errors.New("key not found")
+
+// Document is a parsed config file.
+type Document struct {
+	sections []*section
+	newline  string
+	// godot3 is set when values are written in Godot 3's syntax.
+	godot3 bool
+}
+
+// configVersionGodot4 is the config_version written by Godot 4. Godot 3.0
+// writes 3, and later Godot 3 editors 4.
+const configVersionGodot4 = 5
+
+type section struct {
+	name string
+	// header is the original text of the section header, including its line
+	// ending. It is empty for the global section.
+	header  string
+	entries []*entry
+}
+
+// entry is a key and its value, or a comment or blank line if key is empty.
+type entry struct {
+	key string
+	// prefix is the text before the value, including the key and the "=".
+	prefix string
+	// value is the text of the value, which may span several lines.
+	value string
+	// suffix is the text after the value, up to and including the line ending.
+	suffix string
+}
+
+func (e *entry) text() string {
+	return e.prefix + e.value + e.suffix
+}
+
+func (e *entry) blank() bool {
+	return e.key == "" && strings.TrimSpace(e.suffix) == ""
+}
+
+// Load reads and parses a config file.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	d, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return d, nil
+}
+
+// Parse parses the contents of a config file.
+func Parse(data []byte) (*Document, error) {
+	s := newScanner(string(data))
+
+	d := &Document{
+		sections: []*section{{name: Global}},
+		newline:  lineEnding(string(data)),
+	}
+
+	for !s.done() {
+		current := d.sections[len(d.sections)-1]
+
+		switch c := s.peekLineStart(); {
+		case c == '[':
+			name, header, err := s.header()
+			if err != nil {
+				return nil, err
+			}
+
+			d.sections = append(d.sections, &section{name: name, header: header})
+		case c == 0 || c == ';' || c == '#':
+			current.entries = append(current.entries, &entry{suffix: s.line()})
+		default:
+			e, err := s.entry()
+			if err != nil {
+				return nil, err
+			}
+
+			current.entries = append(current.entries, e)
+		}
+	}
+
+	if raw, ok := d.GetRaw(Global, "config_version"); ok {
+		v, err := strconv.Atoi(raw)
+		d.godot3 = err == nil && v > 0 && v < configVersionGodot4
+	}
+
+	return d, nil
+}
+
+// Godot3 reports whether values are set in Godot 3's syntax. Parse sets it for
+// files whose config_version was written by Godot 3. Other files, such as
+// export_presets.cfg, don't say which version wrote them, and need SetGodot3.
+func (d *Document) Godot3() bool {
+	return d.godot3
+}
+
+// SetGodot3 sets whether values are set in Godot 3's syntax.
+func (d *Document) SetGodot3(godot3 bool) {
+	d.godot3 = godot3
+}
+
+// Bytes returns the contents of the document.
+func (d *Document) Bytes() []byte {
+	var b strings.Builder
+
+	for _, s := range d.sections {
+		b.WriteString(s.header)
+
+		for _, e := range s.entries {
+			b.WriteString(e.text())
+		}
+	}
+
+	return []byte(b.String())
+}
+
+// Save writes the document to a file, keeping the permissions of the file if
+// it already exists.
+func (d *Document) Save(path string) error {
+	mode := os.FileMode(0o644)
+
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := os.WriteFile(path, d.Bytes(), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Sections returns the names of the sections in the order they appear. The
+// global section is only included if it has keys.
+func (d *Document) Sections() []string {
+	var names []string
+
+	for _, s := range d.sections {
+		if s.name == Global && len(s.keys()) == 0 {
+			continue
+		}
+
+		names = append(names, s.name)
+	}
+
+	return names
+}
+
+// RawSection is a section and the text of its values, as returned by Raw.
+type RawSection struct {
+	Name   string
+	Keys   []string
+	Values []string
+}
+
+// Raw returns every section in the order they appear, with the text of their
+// values. Unlike the other methods, sections with the same name are returned
+// separately, as there can be many in scene files.
+func (d *Document) Raw() []RawSection {
+	var sections []RawSection
+
+	for _, s := range d.sections {
+		if s.name == Global && len(s.keys()) == 0 {
+			continue
+		}
+
+		raw := RawSection{Name: s.name}
+
+		for _, e := range s.entries {
+			if e.key != "" {
+				raw.Keys = append(raw.Keys, e.key)
+				raw.Values = append(raw.Values, e.value)
+			}
+		}
+
+		sections = append(sections, raw)
+	}
+
+	return sections
+}
+
+// Keys returns the keys of a section in the order they appear.
+func (d *Document) Keys(name string) []string {
+	s := d.section(name)
+	if s == nil {
+		return nil
+	}
+
+	return s.keys()
+}
+
+// GetRaw returns the text of a value exactly as it is written.
+func (d *Document) GetRaw(name, key string) (string, bool) {
+	e := d.entry(name, key)
+	if e == nil {
+		return "", false
+	}
+
+	return e.value, true
+}
+
+// Get returns a value decoded by variant.Parse.
+func (d *Document) Get(name, key string) (any, error) {
+	raw, ok := d.GetRaw(name, key)
+	if !ok {
+		return nil, fmt.Errorf("%w: [%s] %s", ErrNotFound, name, key)
+	}
+
+	v, err := variant.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of [%s] %s: %w", name, key, err)
+	}
+
+	return v, nil
+}
+
+// Set sets a value, encoded by variant.Format, or variant.FormatGodot3 for
+// Godot 3 files. Missing keys are added after the last key of their section,
+// and missing sections at the end.
+func (d *Document) Set(name, key string, value any) {
+	if d.godot3 {
+		d.SetRaw(name, key, variant.FormatGodot3(value))
+
+		return
+	}
+
+	d.SetRaw(name, key, variant.Format(value))
+}
+
+// SetRaw sets the text of a value, which must be valid in a config file.
+func (d *Document) SetRaw(name, key, raw string) {
+	if e := d.entry(name, key); e != nil {
+		e.value = raw
+
+		return
+	}
+
+	e := &entry{key: key, prefix: formatKey(key) + "=", value: raw, suffix: d.newline}
+
+	s := d.section(name)
+	if s == nil {
+		s = d.addSection(name)
+		s.entries = append(s.entries, e)
+
+		return
+	}
+
+	// After the last key, so that comments and blank lines separating the
+	// section from the next one stay where they are. Godot leaves a blank
+	// line below headers, which a section without keys keeps as well.
+	at := 0
+
+	switch last := s.lastKey(); {
+	case last >= 0:
+		at = last + 1
+	case name == Global:
+		// Below the comments at the top of the file, and above the sections.
+		s.entries = append(s.entries, e)
+
+		if len(d.sections) > 1 {
+			s.entries = append(s.entries, &entry{suffix: d.newline})
+		}
+
+		return
+	case len(s.entries) > 0 && s.entries[0].blank():
+		at = 1
+	}
+
+	s.entries = append(s.entries[:at], append([]*entry{e}, s.entries[at:]...)...)
+}
+
+// Delete removes a key, reporting whether it existed.
+func (d *Document) Delete(name, key string) bool {
+	s := d.section(name)
+	if s == nil {
+		return false
+	}
+
+	for i, e := range s.entries {
+		if e.key == key {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *Document) section(name string) *section {
+	for _, s := range d.sections {
+		if s.name == name {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) entry(name, key string) *entry {
+	s := d.section(name)
+	if s == nil {
+		return nil
+	}
+
+	for _, e := range s.entries {
+		if e.key == key {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// addSection appends a section, separated from the previous one by a blank
+// line as Godot does.
+func (d *Document) addSection(name string) *section {
+	last := d.sections[len(d.sections)-1]
+
+	if text := string(d.Bytes()); text != "" {
+		if !strings.HasSuffix(text, "\n") {
+			last.entries = append(last.entries, &entry{suffix: d.newline})
+		}
+
+		if !strings.HasSuffix(text, "\n\n") && !strings.HasSuffix(text, "\r\n\r\n") {
+			last.entries = append(last.entries, &entry{suffix: d.newline})
+		}
+	}
+
+	s := &section{
+		name:    name,
+		header:  "[" + name + "]" + d.newline,
+		entries: []*entry{{suffix: d.newline}},
+	}
+
+	d.sections = append(d.sections, s)
+
+	return s
+}
+
+// lastKey returns the index of the last key entry, or -1 if there is none.
+func (s *section) lastKey() int {
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if s.entries[i].key != "" {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (s *section) keys() []string {
+	var keys []string
+
+	for _, e := range s.entries {
+		if e.key != "" {
+			keys = append(keys, e.key)
+		}
+	}
+
+	return keys
+}
+
+// formatKey quotes keys that could not be read back otherwise.
+func formatKey(key string) string {
+	if strings.ContainsAny(key, " =\"[];#\t") {
+		return variant.Format(key)
+	}
+
+	return key
+}
+
+// lineEnding returns the line ending used by a file, defaulting to "\n".
+func lineEnding(data string) string {
+	if i := strings.IndexByte(data, '\n'); i > 0 && data[i-1] == '\r' {
+		return "\r\n"
+	}
+
+	return "\n"
+}
@@ -0,0 +1,261 @@
+package document_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/document"
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// project is laid out the way Godot 4 writes project.godot.
+var project = heredoc.Doc(`
+	; Engine configuration file.
+	; It's best edited using the editor UI and not directly,
+	; since the parameters that go here are not all obvious.
+	;
+	; Format:
+	;   [section] ; section goes between []
+	;   param=value ; assign values to parameters
+
+	config_version=5
+
+	[application]
+
+	config/name="Example"
+	config/version="1.0.0"
+	config/features=PackedStringArray("4.3", "GL Compatibility")
+	config/description="Two
+	lines"
+
+	[input]
+
+	jump={
+	"deadzone": 0.5,
+	"events": [Object(InputEventKey,"resource_local_to_scene":false,"keycode":32,"script":null)
+	]
+	}
+
+	[rendering]
+
+	renderer/rendering_method="gl_compatibility"
+`)
+
+func TestParse_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		project,
+		"",
+		"config_version=5",
+		"a = 1 \r\n\r\n[b]\r\nc = \"d\"\r\n",
+		"[section]\n\"key with spaces\"=true\n",
+	} {
+		d, err := document.Parse([]byte(input))
+		require.NoError(t, err)
+		assert.Equal(t, input, string(d.Bytes()))
+	}
+}
+
+func TestDocument_Get(t *testing.T) {
+	t.Parallel()
+
+	d, err := document.Parse([]byte(project))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{document.Global, "application", "input", "rendering"}, d.Sections())
+	assert.Equal(t, []string{"config/name", "config/version", "config/features", "config/description"}, d.Keys("application"))
+
+	v, err := d.Get(document.Global, "config_version")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), v)
+
+	v, err = d.Get("application", "config/description")
+	require.NoError(t, err)
+	assert.Equal(t, "Two\nlines", v)
+
+	v, err = d.Get("input", "jump")
+	require.NoError(t, err)
+	assert.IsType(t, variant.Dictionary{}, v)
+
+	raw, ok := d.GetRaw("application", "config/features")
+	assert.True(t, ok)
+	assert.Equal(t, `PackedStringArray("4.3", "GL Compatibility")`, raw)
+
+	_, err = d.Get("application", "missing")
+	assert.ErrorIs(t, err, document.ErrNotFound)
+}
+
+func TestDocument_Set(t *testing.T) {
+	t.Parallel()
+
+	d, err := document.Parse([]byte(project))
+	require.NoError(t, err)
+
+	d.Set("application", "config/version", "1.2.3")
+	d.Set("application", "config/description", "One line")
+	d.Set("application", "run/main_scene", "res://main.tscn")
+	d.Set("display", "window/size/viewport_width", 1280)
+	d.Set(document.Global, "config_version", 5)
+	assert.True(t, d.Delete("rendering", "renderer/rendering_method"))
+	assert.False(t, d.Delete("rendering", "renderer/rendering_method"))
+
+	want := heredoc.Doc(`
+		; Engine configuration file.
+		; It's best edited using the editor UI and not directly,
+		; since the parameters that go here are not all obvious.
+		;
+		; Format:
+		;   [section] ; section goes between []
+		;   param=value ; assign values to parameters
+
+		config_version=5
+
+		[application]
+
+		config/name="Example"
+		config/version="1.2.3"
+		config/features=PackedStringArray("4.3", "GL Compatibility")
+		config/description="One line"
+		run/main_scene="res://main.tscn"
+
+		[input]
+
+		jump={
+		"deadzone": 0.5,
+		"events": [Object(InputEventKey,"resource_local_to_scene":false,"keycode":32,"script":null)
+		]
+		}
+
+		[rendering]
+
+
+		[display]
+
+		window/size/viewport_width=1280
+	`)
+
+	assert.Equal(t, want, string(d.Bytes()))
+}
+
+func TestDocument_SetNewFile(t *testing.T) {
+	t.Parallel()
+
+	d, err := document.Parse(nil)
+	require.NoError(t, err)
+
+	d.Set(document.Global, "config_version", 5)
+	d.Set("preset.0", "name", "Linux")
+	d.Set("preset.0", "runnable", true)
+	d.Set("section", "\"quoted\" key", 1)
+
+	assert.Equal(t, heredoc.Doc(`
+		config_version=5
+
+		[preset.0]
+
+		name="Linux"
+		runnable=true
+
+		[section]
+
+		"\"quoted\" key"=1
+	`), string(d.Bytes()))
+
+	reparsed, err := document.Parse(d.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"\"quoted\" key"}, reparsed.Keys("section"))
+}
+
+func TestDocument_SetGodot3(t *testing.T) {
+	t.Parallel()
+
+	// Godot 3.0 wrote config_version=3, and later Godot 3 editors 4.
+	for _, version := range []string{"3", "4"} {
+		d, err := document.Parse([]byte("config_version=" + version + "\n\n[application]\n\nconfig/name=\"Old\"\n"))
+		require.NoError(t, err)
+		assert.True(t, d.Godot3())
+
+		d.Set("application", "run/main_scene", "res://main.tscn")
+		d.Set("editor_plugins", "enabled", []string{"res://addons/gut/plugin.cfg"})
+		d.Set("display", "window/size/width", int64(1280))
+		d.Set("debug", "target", variant.NodePath("Player"))
+
+		assert.Equal(t, heredoc.Doc(`
+			config_version=`+version+`
+
+			[application]
+
+			config/name="Old"
+			run/main_scene="res://main.tscn"
+
+			[editor_plugins]
+
+			enabled=PoolStringArray( "res://addons/gut/plugin.cfg" )
+
+			[display]
+
+			window/size/width=1280
+
+			[debug]
+
+			target=NodePath("Player")
+		`), string(d.Bytes()))
+	}
+
+	// export_presets.cfg doesn't have a config_version.
+	d, err := document.Parse([]byte("[preset.0]\n\nname=\"Linux\"\n"))
+	require.NoError(t, err)
+	assert.False(t, d.Godot3())
+
+	d.SetGodot3(true)
+	d.Set("preset.0", "patch_list", []string{})
+	assert.Equal(t, "[preset.0]\n\nname=\"Linux\"\npatch_list=PoolStringArray(  )\n", string(d.Bytes()))
+
+	d, err = document.Parse([]byte(project))
+	require.NoError(t, err)
+	assert.False(t, d.Godot3())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"[section\nkey=1",
+		"just text",
+		"key=\"unterminated",
+		"key={\n\"a\": 1\n",
+		"=1",
+	} {
+		_, err := document.Parse([]byte(input))
+		assert.ErrorIs(t, err, document.ErrSyntax, input)
+	}
+}
+
+func TestDocument_Save(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "project.godot")
+	require.NoError(t, os.WriteFile(path, []byte(project), 0o600))
+
+	d, err := document.Load(path)
+	require.NoError(t, err)
+
+	d.Set("application", "config/version", "2.0.0")
+	require.NoError(t, d.Save(path))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	reloaded, err := document.Load(path)
+	require.NoError(t, err)
+
+	v, err := reloaded.Get("application", "config/version")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", v)
+}
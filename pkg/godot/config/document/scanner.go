@@ -0,0 +1,275 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
+)
+
+// ErrSyntax is wrapped by the errors returned for files that cannot be parsed.
+var ErrSyntax = errors.New("invalid config file")
+
+// scanner splits a config file into headers, entries and other lines.
+type scanner struct {
+	s      string
+	pos    int
+	lineNo int
+}
+
+func newScanner(s string) *scanner {
+	return &scanner{s: s, lineNo: 1}
+}
+
+func (s *scanner) done() bool {
+	return s.pos >= len(s.s)
+}
+
+func (s *scanner) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: line %d: %s", ErrSyntax, s.lineNo, fmt.Sprintf(format, args...))
+}
+
+// peekLineStart returns the first character of the current line that isn't
+// whitespace, or 0 if the line is blank.
+func (s *scanner) peekLineStart() byte {
+	for i := s.pos; i < len(s.s); i++ {
+		switch c := s.s[i]; c {
+		case ' ', '\t', '\r':
+		case '\n':
+			return 0
+		default:
+			return c
+		}
+	}
+
+	return 0
+}
+
+// line consumes the rest of the current line, including its line ending.
+func (s *scanner) line() string {
+	start := s.pos
+
+	if i := strings.IndexByte(s.s[s.pos:], '\n'); i >= 0 {
+		s.pos += i + 1
+	} else {
+		s.pos = len(s.s)
+	}
+
+	text := s.s[start:s.pos]
+	s.lineNo += strings.Count(text, "\n")
+
+	return text
+}
+
+// header consumes a section header, returning the name of the section and the
+// text of the line. Headers may span several lines, as Godot 3 writes the
+// groups of a node one per line.
+func (s *scanner) header() (string, string, error) {
+	start := s.pos
+	bracket := start + strings.IndexByte(s.s[start:], '[')
+
+	end := s.headerEnd(bracket)
+	if end < 0 {
+		return "", "", s.errorf("unterminated section header %q", strings.TrimSpace(s.line()))
+	}
+
+	s.pos = end
+	s.lineNo += strings.Count(s.s[start:end], "\n")
+	text := s.s[start:end] + s.line()
+
+	return strings.TrimSpace(s.s[bracket+1 : end]), text, nil
+}
+
+// headerEnd returns the offset of the bracket closing the section header that
+// opens at start, or -1 if there is none.
+func (s *scanner) headerEnd(start int) int {
+	depth := 0
+
+	for i := start; i < len(s.s); i++ {
+		switch c := s.s[i]; c {
+		case '"':
+			end := closingQuote(s.s[i:])
+			if end < 0 {
+				return -1
+			}
+
+			i += end
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				return i
+			}
+		case '\n':
+			// Only brackets left open continue the header.
+			if depth <= 1 {
+				return -1
+			}
+		}
+	}
+
+	return -1
+}
+
+// entry consumes a key and its value, which may span several lines.
+func (s *scanner) entry() (*entry, error) {
+	start := s.pos
+	rest := s.s[s.pos:]
+	lineEnd := strings.IndexByte(rest, '\n')
+
+	if lineEnd < 0 {
+		lineEnd = len(rest)
+	}
+
+	key, eq, err := s.key(rest[:lineEnd])
+	if err != nil {
+		return nil, err
+	}
+
+	valueStart := start + eq + 1
+
+	for valueStart < len(s.s) && (s.s[valueStart] == ' ' || s.s[valueStart] == '\t') {
+		valueStart++
+	}
+
+	valueEnd, err := s.valueEnd(valueStart)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trailing whitespace belongs with the line ending rather than the value.
+	value := strings.TrimRight(s.s[valueStart:valueEnd], " \t\r")
+
+	s.pos = valueStart + len(value)
+	suffix := s.line()
+
+	s.lineNo += strings.Count(value, "\n")
+
+	return &entry{
+		key:    key,
+		prefix: s.s[start:valueStart],
+		value:  value,
+		suffix: suffix,
+	}, nil
+}
+
+// key parses the key of an entry line, returning it and the offset of the "="
+// that follows it. Keys with special characters are quoted.
+func (s *scanner) key(line string) (string, int, error) {
+	trimmed := strings.TrimLeft(line, " \t")
+	indent := len(line) - len(trimmed)
+
+	if strings.HasPrefix(trimmed, `"`) {
+		end := closingQuote(trimmed)
+		if end < 0 {
+			return "", 0, s.errorf("unterminated key %q", trimmed)
+		}
+
+		key, err := variant.Parse(trimmed[:end+1])
+		if err != nil {
+			return "", 0, s.errorf("invalid key %q", trimmed[:end+1])
+		}
+
+		eq := strings.IndexByte(trimmed[end+1:], '=')
+		if eq < 0 || strings.TrimSpace(trimmed[end+1:end+1+eq]) != "" {
+			return "", 0, s.errorf("expected \"=\" after key %q", trimmed[:end+1])
+		}
+
+		return key.(string), indent + end + 1 + eq, nil //nolint:forcetypeassert
+	}
+
+	eq := strings.IndexByte(line, '=')
+	if eq < 0 {
+		return "", 0, s.errorf("expected key=value, got %q", strings.TrimSpace(line))
+	}
+
+	key := strings.TrimSpace(line[:eq])
+	if key == "" {
+		return "", 0, s.errorf("missing key before \"=\"")
+	}
+
+	return key, eq, nil
+}
+
+// valueEnd returns the offset of the line ending or comment after a value.
+// Values end at the first line ending outside of strings and brackets.
+// Comments start with ";", or with "#" after whitespace, and run to the end of
+// the line; those inside brackets are left in the value.
+//
+//nolint:cyclop
+func (s *scanner) valueEnd(start int) (int, error) {
+	depth := 0
+	inString := false
+
+	for i := start; i < len(s.s); i++ {
+		c := s.s[i]
+
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ';', '#':
+			if c == '#' && (i == start || !isSpace(s.s[i-1])) {
+				continue
+			}
+
+			if depth <= 0 {
+				return i, nil
+			}
+
+			if end := strings.IndexByte(s.s[i:], '\n'); end >= 0 {
+				i += end - 1
+			} else {
+				i = len(s.s)
+			}
+		case '\n':
+			if depth <= 0 {
+				return i, nil
+			}
+		}
+	}
+
+	if inString {
+		return 0, s.errorf("unterminated string")
+	}
+
+	if depth > 0 {
+		return 0, s.errorf("unterminated value")
+	}
+
+	return len(s.s), nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// closingQuote returns the offset of the quote ending the string that starts
+// the text, or -1 if there is none.
+func closingQuote(text string) int {
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return -1
+}
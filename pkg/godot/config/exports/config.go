@@ -4,8 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 
-	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/providers/structs"
 	"github.com/knadh/koanf/v2"
 	"github.com/ruffel/godotreleaser/pkg/godot/config/parser"
@@ -38,6 +38,7 @@ type PresetOptions struct {
 	CustomTemplateRelease         string   `koanf:"custom_template/release"`
 	DebugExportConsoleWrapper     int      `koanf:"debug_export/console_wrapper"`
 	BinaryFormatArchitecture      string   `koanf:"binary_format/architecture"`
+	BinaryFormat64Bits            bool     `koanf:"binary_format/64_bits"` // Godot 3 only.
 	BinaryFormatEmbedPCK          bool     `koanf:"binary_format/embed_pck"`
 	TextureFormatBPTC             bool     `koanf:"texture_format/bptc"`
 	TextureFormatS3TC             bool     `koanf:"texture_format/s3tc"`
@@ -132,13 +133,30 @@ func (c *Config) Render() (string, error) {
 	return string(data), nil
 }
 
+// ErrNoPresets is returned for export_presets.cfg files without any presets.
+var ErrNoPresets = errors.New("no valid presets found")
+
 // New loads the configuration from the specified file and returns a Config object.
 func New(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file %s: %w", path, err)
+	}
+
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Parse parses the contents of an export_presets.cfg file.
+func Parse(data []byte) (*Config, error) {
 	k := koanf.New(".")
 
-	// Load configuration file using the specified parser.
-	if err := k.Load(file.Provider(path), parser.Godot{}); err != nil {
-		return nil, fmt.Errorf("failed to load config file %s: %w", path, err)
+	if err := k.Load(parser.Bytes(data), parser.Godot{}); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// Parse the presets from the configuration.
@@ -186,7 +204,7 @@ func loadPresets(k *koanf.Koanf) (PresetCollection, error) {
 	}
 
 	if len(presets) == 0 {
-		return nil, errors.New("no valid presets found")
+		return nil, ErrNoPresets
 	}
 
 	return presets, nil
@@ -1,11 +1,18 @@
 package parser_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/MakeNowJust/heredoc"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/document"
 	"github.com/ruffel/godotreleaser/pkg/godot/config/parser"
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParser_Unmarshal_Simple(t *testing.T) {
@@ -24,7 +31,7 @@ func TestParser_Unmarshal_Simple(t *testing.T) {
 			`),
 			want: map[string]interface{}{
 				"DEFAULT": map[string]interface{}{
-					"config_version": "5",
+					"config_version": int64(5),
 				},
 			},
 		},
@@ -39,7 +46,7 @@ func TestParser_Unmarshal_Simple(t *testing.T) {
 			want: map[string]interface{}{
 				"DEFAULT": map[string]interface{}{},
 				"preset.0.options": map[string]interface{}{
-					"ssh_remote_deploy/cleanup_script": "line1__NEWLINE__line2__NEWLINE__line3",
+					"ssh_remote_deploy/cleanup_script": "line1\nline2\nline3",
 				},
 			},
 		},
@@ -93,6 +100,30 @@ func TestParser_Unmarshal_Simple(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "good - PoolStringArray(...)",
+			input: heredoc.Doc(`
+				[DEFAULT]
+				array = PoolStringArray( "foo", "bar" )
+			`),
+			want: map[string]interface{}{
+				"DEFAULT": map[string]interface{}{
+					"array": []string{"foo", "bar"},
+				},
+			},
+		},
+		{
+			name: "good - PoolStringArray(empty)",
+			input: heredoc.Doc(`
+				[DEFAULT]
+				array = PoolStringArray(  )
+			`),
+			want: map[string]interface{}{
+				"DEFAULT": map[string]interface{}{
+					"array": []string{},
+				},
+			},
+		},
 		{
 			name: "good - inline JSON",
 			input: heredoc.Doc(`
@@ -101,7 +132,7 @@ func TestParser_Unmarshal_Simple(t *testing.T) {
 			`),
 			want: map[string]interface{}{
 				"DEFAULT": map[string]interface{}{
-					"json": `{"foo": "bar", "baz": 42 }`,
+					"json": variant.Dictionary{{Key: "foo", Value: "bar"}, {Key: "baz", Value: int64(42)}},
 				},
 			},
 		},
@@ -115,7 +146,48 @@ func TestParser_Unmarshal_Simple(t *testing.T) {
 				}`),
 			want: map[string]interface{}{
 				"DEFAULT": map[string]interface{}{
-					"json": `{ "foo": "bar", "baz": 42 }`,
+					"json": variant.Dictionary{{Key: "foo", Value: "bar"}, {Key: "baz", Value: int64(42)}},
+				},
+			},
+		},
+		{
+			name: "good - typed values",
+			input: heredoc.Doc(`
+				[display]
+				window/size/viewport_width=1280
+				window/stretch/scale=1.5
+				window/vsync/vsync_mode=false
+				config/name="Say \"hi\""
+				config/icon=&"icon"
+				config/size=Vector2i(1280, 720)
+				config/color=Color(0.3, 0.3, 0.3, 1)
+				config/nothing=null
+			`),
+			want: map[string]interface{}{
+				"DEFAULT": map[string]interface{}{},
+				"display": map[string]interface{}{
+					"window/size/viewport_width": int64(1280),
+					"window/stretch/scale":       1.5,
+					"window/vsync/vsync_mode":    false,
+					"config/name":                `Say "hi"`,
+					"config/icon":                variant.StringName("icon"),
+					"config/size":                variant.Vector2i{X: 1280, Y: 720},
+					"config/color":               variant.Color{R: 0.3, G: 0.3, B: 0.3, A: 1},
+					"config/nothing":             nil,
+				},
+			},
+		},
+		{
+			name: "good - quoted numbers stay strings",
+			input: heredoc.Doc(`
+				[DEFAULT]
+				port="22"
+				empty=""
+			`),
+			want: map[string]interface{}{
+				"DEFAULT": map[string]interface{}{
+					"port":  "22",
+					"empty": "",
 				},
 			},
 		},
@@ -386,3 +458,191 @@ func TestParser_Marshal_Simple(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_Koanf(t *testing.T) {
+	t.Parallel()
+
+	input := heredoc.Doc(`
+		[application]
+		config/features=PackedStringArray("4.3", "Forward Plus")
+
+		[input]
+		jump={
+		"deadzone": 0.5,
+		"events": []
+		}
+	`)
+
+	path := filepath.Join(t.TempDir(), "project.godot")
+	require.NoError(t, os.WriteFile(path, []byte(input), 0o600))
+
+	k := koanf.New(".")
+	require.NoError(t, k.Load(file.Provider(path), parser.Godot{}))
+
+	// Dictionaries are values, not sections to be flattened into more keys.
+	assert.ElementsMatch(t, []string{"DEFAULT", "application.config/features", "input.jump"}, k.Keys())
+	assert.Equal(t, []string{"4.3", "Forward Plus"}, k.Get("application.config/features"))
+
+	jump, ok := k.Get("input.jump").(variant.Dictionary)
+	require.True(t, ok)
+
+	deadzone, _ := jump.Get("deadzone")
+	assert.InDelta(t, 0.5, deadzone, 0)
+}
+
+// The conformance corpus holds project.godot and export_presets.cfg files saved
+// by Godot editors, hand-written files in the form of Godot 3 and 4, and files
+// with values that are easy to misread. See testdata/conformance/README.md.
+func TestParser_Conformance(t *testing.T) {
+	t.Parallel()
+
+	var paths []string
+
+	require.NoError(t, filepath.WalkDir("testdata/conformance", func(path string, d os.DirEntry, err error) error {
+		if ext := filepath.Ext(path); err == nil && !d.IsDir() && (ext == ".godot" || ext == ".cfg") {
+			paths = append(paths, path)
+		}
+
+		return err
+	}))
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			_, err = parser.Godot{}.Unmarshal(data)
+			require.NoError(t, err)
+
+			doc, err := document.Parse(data)
+			require.NoError(t, err)
+			assert.Equal(t, string(data), string(doc.Bytes()))
+
+			// Every value in the corpus is valid, so none are left as text.
+			for _, section := range doc.Raw() {
+				for i, key := range section.Keys {
+					_, err := variant.Parse(section.Values[i])
+					assert.NoError(t, err, "[%s] %s", section.Name, key)
+				}
+			}
+		})
+	}
+}
+
+// Every editor-saved file must record the editor that saved it and its source.
+func TestParser_Conformance_Sources(t *testing.T) {
+	t.Parallel()
+
+	sources, err := os.ReadFile("testdata/conformance/editor/README.md")
+	require.NoError(t, err)
+
+	require.NoError(t, filepath.WalkDir("testdata/conformance/editor", func(path string, d os.DirEntry, err error) error {
+		if ext := filepath.Ext(path); err == nil && !d.IsDir() && (ext == ".godot" || ext == ".cfg") {
+			rel, err := filepath.Rel("testdata/conformance/editor", path)
+			require.NoError(t, err)
+			assert.Contains(t, string(sources), "| `"+filepath.ToSlash(rel)+"` |", "%s is not listed in editor/README.md", path)
+		}
+
+		return err
+	}))
+}
+
+func TestParser_Conformance_Values(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path    string
+		section string
+		key     string
+		want    any
+	}{
+		{"handwritten/godot3/platformer/project.godot", "DEFAULT", "config_version", int64(4)},
+		{"handwritten/godot3/platformer/project.godot", "application", "config/description", `A "simple" platformer, with {braces} and [brackets].`},
+		{"handwritten/godot3/platformer/project.godot", "editor_plugins", "enabled", []string{"res://addons/dialogue/plugin.cfg", "res://addons/gut/plugin.cfg"}},
+		{"handwritten/godot3/platformer/project.godot", "physics", "2d/default_gravity_vector", variant.Vector2{Y: 1}},
+		{"handwritten/godot3/platformer/export_presets.cfg", "preset.0.options", "codesign/custom_options", []string{}},
+		{"handwritten/godot3/platformer/export_presets.cfg", "preset.0.options", "application/file_description", "A platformer; with a semicolon"},
+		{"handwritten/godot3/platformer/export_presets.cfg", "preset.2.options", "html/head_include", "<script>\n  window.onerror = function(msg) { console.error(\"boot: \" + msg); };\n</script>"},
+		{"handwritten/godot3/platformer/export_presets.cfg", "preset.3.options", "version/code", int64(12)},
+		{"handwritten/godot3/mono/project.godot", "mono", "project/assembly_name", "MonoTactics"},
+		{"handwritten/godot4/shooter/project.godot", "application", "config/description", "Top-down shooter.\nSecond line of the description, with a \"quote\"."},
+		{"handwritten/godot4/shooter/project.godot", "application", "config/features", []string{"4.3", "Forward Plus"}},
+		{"handwritten/godot4/shooter/export_presets.cfg", "preset.1.options", "ssh_remote_deploy/cleanup_script", "#!/usr/bin/env bash\nkill $(pgrep -x -f \"{temp_dir}/{exe_name} {cmd_args}\")\nrm -rf \"{temp_dir}\""},
+		{"handwritten/godot4/shooter/export_presets.cfg", "preset.2.options", "html/head_include", "<style>\n  body { background: #101014; }\n</style>"},
+		{"handwritten/godot4/shooter/export_presets.cfg", "preset.3.options", "application/copyright_localized", variant.Dictionary{{Key: "de", Value: "© Example Studio"}}},
+		{"handwritten/godot4/shooter/export_presets.cfg", "preset.4", "export_files", []string{"res://scenes/main.tscn", "res://ui/hud.tscn"}},
+		{"handwritten/godot4/csharp/project.godot", "dotnet", "project/assembly_name", "CardBattler"},
+		{"handwritten/godot4/csharp/export_presets.cfg", "preset.0.options", "storyboard/custom_bg_color", variant.Color{A: 1}},
+		{"handwritten/godot3/v3.0/project.godot", "DEFAULT", "config_version", int64(3)},
+		{"handwritten/godot3/v3.0/export_presets.cfg", "preset.0", "patch_list", []string{}},
+		{"handwritten/godot3/v3.2/project.godot", "DEFAULT", "_global_script_class_icons", variant.Dictionary{
+			{Key: "Inventory", Value: "res://items/inventory.svg"},
+			{Key: "Pickup", Value: ""},
+		}},
+		{"handwritten/godot3/v3.2/export_presets.cfg", "preset.0.options", "html/head_include", `<link rel="manifest" href="manifest.json">`},
+		{"handwritten/godot3/v3.2/export_presets.cfg", "preset.1.options", "permissions/custom_permissions", []string{}},
+		{"handwritten/godot4/v4.0/project.godot", "application", "config/features", []string{"4.0", "Mobile"}},
+		{"handwritten/godot4/v4.2/project.godot", "dotnet", "project/assembly_name", "Farm Sim"},
+		{"handwritten/godot4/v4.2/export_presets.cfg", "preset.0.options", "codesign/custom_options", []string{}},
+		{"handwritten/godot4/v4.4/project.godot", "application", "run/main_scene", "uid://bq3w8ymg6l1yx"},
+		{"handwritten/godot4/v4.4/export_presets.cfg", "preset.1", "export_files", []string{"res://tests/test_waves.gd"}},
+		{"handwritten/godot4/v4.4/export_presets.cfg", "preset.1.options", "progressive_web_app/background_color", variant.Color{A: 1}},
+		{"handwritten/edge/tricky.cfg", "strings", "quoted", `a "quoted" word`},
+		{"handwritten/edge/tricky.cfg", "strings", "hashed", `a "quoted" word`},
+		{"handwritten/edge/tricky.cfg", "strings", "ends_escaped", "first line\nsecond line ends with a \"quote\""},
+		{"handwritten/edge/tricky.cfg", "strings", "only_escaped", `"`},
+		{"handwritten/edge/tricky.cfg", "strings", "opening_brace", "{ not a dictionary"},
+		{"handwritten/edge/tricky.cfg", "strings", "closing_brace", "not a dictionary }"},
+		{"handwritten/edge/tricky.cfg", "strings", "header_lookalike", "\n[section]\nkey=value"},
+		{"handwritten/edge/tricky.cfg", "strings", "equals", "a=b"},
+		{"handwritten/edge/tricky.cfg", "strings", "comment_chars", "a;b # c"},
+		{"handwritten/edge/tricky.cfg", "strings", "unicode", "héllo é 😀"},
+		{"handwritten/edge/tricky.cfg", "strings", "backslash", `C:\path\`},
+		{"handwritten/edge/tricky.cfg", "strings", "indented", "leading whitespace"},
+		{"handwritten/edge/tricky.cfg", "values", "dictionary", variant.Dictionary{
+			{Key: "open", Value: "{"},
+			{Key: "close", Value: "}"},
+			{Key: "text", Value: `line with "quotes" and ] bracket`},
+		}},
+		{"handwritten/edge/tricky.cfg", "values", "array", []any{int64(1), int64(2), int64(3)}},
+		{"handwritten/edge/tricky.cfg", "values", "quoted/key", true},
+		{"handwritten/edge/tricky.cfg", "values", "node", variant.NodePath("Path/To:property")},
+		{"handwritten/edge/tricky.cfg", "values", "name", variant.StringName("ui_cancel")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+"/"+tt.section+"/"+tt.key, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(filepath.Join("testdata/conformance", tt.path))
+			require.NoError(t, err)
+
+			got, err := parser.Godot{}.Unmarshal(data)
+			require.NoError(t, err)
+
+			section, ok := got[tt.section].(map[string]interface{})
+			require.True(t, ok)
+			assert.Equal(t, tt.want, section[tt.key])
+		})
+	}
+}
+
+func TestParser_Unmarshal_CRLF(t *testing.T) {
+	t.Parallel()
+
+	input := "config_version=5\r\n\r\n[application]\r\n\r\nconfig/name=\"CRLF\"\r\n" +
+		"config/features=PackedStringArray(\"4.2\",\r\n\"Forward Plus\")\r\n"
+
+	got, err := parser.Godot{}.Unmarshal([]byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"DEFAULT": map[string]interface{}{"config_version": int64(5)},
+		"application": map[string]interface{}{
+			"config/name":     "CRLF",
+			"config/features": []string{"4.2", "Forward Plus"},
+		},
+	}, got)
+}
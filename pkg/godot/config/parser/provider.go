@@ -0,0 +1,15 @@
+package parser
+
+import "errors"
+
+// Bytes is a koanf provider for the contents of a file that has already been
+// read, to be parsed by Godot.
+type Bytes []byte
+
+func (b Bytes) ReadBytes() ([]byte, error) {
+	return b, nil
+}
+
+func (b Bytes) Read() (map[string]interface{}, error) {
+	return nil, errors.New("parser.Bytes does not support Read()")
+}
@@ -0,0 +1,3 @@
+extends Node
+
+signal wave_started(number: int)
@@ -2,12 +2,12 @@ package project
 
 import (
 	"fmt"
+	"os"
 	"sort"
-	"strings"
 
 	"github.com/hashicorp/go-version"
-	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/v2"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/document"
 	"github.com/ruffel/godotreleaser/pkg/godot/config/parser"
 	"github.com/samber/lo"
 )
@@ -18,13 +18,33 @@ type Config struct {
 	Features  []string     `koanf:"application.config/features"`
 	MainScene string       `koanf:"application.run/main_scene"`
 	raw       *koanf.Koanf `koanf:"-"`
+	// doc keeps the order of keys, which the settings accessors rely on.
+	doc *document.Document `koanf:"-"`
 }
 
+// ConfigVersionGodot4 is the config_version written by Godot 4 editors. Godot
+// 3.0 writes 3, and later Godot 3 editors 4.
+const ConfigVersionGodot4 = 5
+
+// IsGodot3ConfigVersion reports whether a config_version was written by a Godot
+// 3 editor.
+func IsGodot3ConfigVersion(version int) bool {
+	return version > 0 && version < ConfigVersionGodot4
+}
+
+// ContainsMono reports whether project.godot has a [dotnet] section, or a
+// [mono] section in Godot 3. Not every C# project has one, so DetectDotNet
+// should be preferred.
 func (c *Config) ContainsMono() bool {
-	// TODO: This is a bit hacky
-	return lo.ContainsBy(c.raw.Keys(), func(k string) bool {
-		return strings.HasPrefix(k, "dotnet")
-	})
+	_, ok := c.dotNetSection()
+
+	return ok
+}
+
+// IsGodot3 reports whether the project was last saved by a Godot 3 editor.
+// Unlike Godot 4, these projects don't record the engine version.
+func (c *Config) IsGodot3() bool {
+	return IsGodot3ConfigVersion(c.Version)
 }
 
 func (c *Config) EngineVersion() *version.Version {
@@ -47,9 +67,19 @@ func (c *Config) EngineVersion() *version.Version {
 }
 
 func New(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses the contents of a project.godot file.
+func Parse(data []byte) (*Config, error) {
 	k := koanf.New(".")
 
-	if err := k.Load(file.Provider(path), parser.Godot{}); err != nil {
+	if err := k.Load(parser.Bytes(data), parser.Godot{}); err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
@@ -59,7 +89,13 @@ func New(path string) (*Config, error) {
 		return nil, err //nolint:wrapcheck
 	}
 
+	doc, err := document.Parse(data)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
 	config.raw = k
+	config.doc = doc
 
 	return config, nil
 }
@@ -0,0 +1,213 @@
+package project_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/project"
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func load(t *testing.T, content string) *project.Config {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "project.godot")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	config, err := project.New(path)
+	require.NoError(t, err)
+
+	return config
+}
+
+func TestConfig_Settings(t *testing.T) {
+	t.Parallel()
+
+	config := load(t, heredoc.Doc(`
+		config_version=5
+
+		[application]
+
+		config/name="Arena Shooter"
+		config/version="0.9.1"
+		config/features=PackedStringArray("4.3", "Mobile")
+		config/icon="res://icon.svg"
+
+		[autoload]
+
+		SaveGame="*res://autoload/save_game.gd"
+		Events="res://autoload/events.gd"
+
+		[display]
+
+		window/size/viewport_width=1920
+		window/stretch/mode="canvas_items"
+
+		[dotnet]
+
+		project/assembly_name="ArenaShooter"
+
+		[editor_plugins]
+
+		enabled=PackedStringArray("res://addons/gut/plugin.cfg")
+
+		[input]
+
+		jump={
+		"deadzone": 0.2,
+		"events": [Object(InputEventKey,"physical_keycode":32,"script":null)
+		]
+		}
+		pause={
+		"deadzone": 0.5,
+		"events": []
+		}
+
+		[internationalization]
+
+		locale/translations=PackedStringArray("res://i18n/text.en.translation", "res://i18n/text.fr.translation", "res://i18n/de.po")
+
+		[rendering]
+
+		renderer/rendering_method="mobile"
+		renderer/rendering_method.mobile="gl_compatibility"
+	`))
+
+	assert.Equal(t, []project.Autoload{
+		{Name: "SaveGame", Path: "res://autoload/save_game.gd", Singleton: true},
+		{Name: "Events", Path: "res://autoload/events.gd"},
+	}, config.Autoloads())
+
+	actions := config.InputActions()
+	require.Len(t, actions, 2)
+	assert.Equal(t, "jump", actions[0].Name)
+	assert.InDelta(t, 0.2, actions[0].Deadzone, 0)
+	require.Len(t, actions[0].Events, 1)
+	assert.Equal(t, "InputEventKey", actions[0].Events[0].Class)
+	assert.Empty(t, actions[1].Events)
+
+	width, height := config.WindowSize()
+	assert.Equal(t, int64(1920), width)
+	assert.Equal(t, int64(648), height)
+
+	assert.Equal(t, "canvas_items", config.StretchMode())
+	assert.Equal(t, "mobile", config.RenderingMethod())
+	assert.Equal(t, "res://icon.svg", config.Icon())
+	assert.Equal(t, "0.9.1", config.ApplicationVersion())
+	assert.Equal(t, []string{"res://addons/gut/plugin.cfg"}, config.EditorPlugins())
+	assert.Equal(t, "ArenaShooter", config.AssemblyName())
+	assert.Equal(t, []string{"en", "fr", "de"}, config.Locales())
+
+	v, ok := config.Get("rendering/renderer/rendering_method.mobile")
+	require.True(t, ok)
+	assert.Equal(t, "gl_compatibility", v)
+
+	v, ok = config.Get("config_version")
+	require.True(t, ok)
+	assert.Equal(t, int64(5), v)
+
+	v, ok = config.Get("application/config/features")
+	require.True(t, ok)
+	assert.Equal(t, []string{"4.3", "Mobile"}, v)
+
+	v, ok = config.Get("input/pause")
+	require.True(t, ok)
+	assert.IsType(t, variant.Dictionary{}, v)
+
+	_, ok = config.Get("application/config/missing")
+	assert.False(t, ok)
+}
+
+func TestConfig_Settings_Defaults(t *testing.T) {
+	t.Parallel()
+
+	config := load(t, heredoc.Doc(`
+		config_version=5
+
+		[application]
+
+		config/name="Empty"
+	`))
+
+	assert.Empty(t, config.Autoloads())
+	assert.Empty(t, config.InputActions())
+
+	width, height := config.WindowSize()
+	assert.Equal(t, int64(1152), width)
+	assert.Equal(t, int64(648), height)
+
+	assert.Equal(t, "disabled", config.StretchMode())
+	assert.Equal(t, "forward_plus", config.RenderingMethod())
+	assert.Equal(t, "Empty", config.AssemblyName())
+	assert.Empty(t, config.ApplicationVersion())
+	assert.Empty(t, config.Locales())
+}
+
+func TestConfig_Settings_Godot3(t *testing.T) {
+	t.Parallel()
+
+	config := load(t, heredoc.Doc(`
+		config_version=4
+
+		[application]
+
+		config/name="Mono Tactics"
+
+		[display]
+
+		window/size/width=1280
+		window/stretch/mode="2d"
+
+		[locale]
+
+		translations=PoolStringArray( "res://locale/text.en.translation" )
+
+		[mono]
+
+		project/assembly_name="MonoTactics"
+
+		[rendering]
+
+		quality/driver/driver_name="GLES2"
+	`))
+
+	width, height := config.WindowSize()
+	assert.Equal(t, int64(1280), width)
+	assert.Equal(t, int64(600), height)
+
+	assert.Equal(t, "2d", config.StretchMode())
+	assert.Equal(t, "GLES2", config.RenderingMethod())
+	assert.Equal(t, "MonoTactics", config.AssemblyName())
+	assert.Equal(t, []string{"en"}, config.Locales())
+}
+
+func TestConfig_Godot30(t *testing.T) {
+	t.Parallel()
+
+	// Godot 3.0 wrote config_version=3, and 3.1 and later 4.
+	config, err := project.New("../parser/testdata/conformance/handwritten/godot3/v3.0/project.godot")
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, config.Version)
+	assert.True(t, config.IsGodot3())
+	assert.Nil(t, config.EngineVersion())
+
+	width, height := config.WindowSize()
+	assert.Equal(t, int64(640), width)
+	assert.Equal(t, int64(480), height)
+	assert.Equal(t, "GLES2", config.RenderingMethod())
+	assert.Equal(t, "Breakout", config.AssemblyName())
+}
+
+func TestIsGodot3ConfigVersion(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, project.IsGodot3ConfigVersion(0), "missing")
+	assert.True(t, project.IsGodot3ConfigVersion(3), "Godot 3.0")
+	assert.True(t, project.IsGodot3ConfigVersion(4), "Godot 3.1 and later")
+	assert.False(t, project.IsGodot3ConfigVersion(project.ConfigVersionGodot4))
+}
@@ -0,0 +1,198 @@
+package project
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// DotNet is the result of DetectDotNet.
+type DotNet struct {
+	// Reasons describes each sign of C# that was found, and is empty if the
+	// project doesn't use C#.
+	Reasons []string
+}
+
+// Enabled reports whether the project uses C#, and needs the .NET build of
+// Godot to be exported.
+func (d DotNet) Enabled() bool {
+	return len(d.Reasons) > 0
+}
+
+// slnProject matches the project entries of a .sln file, capturing the path of
+// the project.
+var slnProject = regexp.MustCompile(`(?m)^Project\([^)]*\)\s*=\s*"[^"]*",\s*"([^"]+)"`) //nolint:gochecknoglobals
+
+// DetectDotNet looks for signs that a project uses C#: a [dotnet] section in
+// project.godot ([mono] in Godot 3), .csproj files using Godot's SDK and the
+// .sln files that include them, and C# scripts used by scenes and resources.
+// fsys is the directory of the project; files that can't be read are skipped.
+//
+// The cheapest signs are looked for first, and DetectDotNet stops at the first
+// one found. Use ExplainDotNet to find every sign.
+func DetectDotNet(fsys fs.FS, config *Config) DotNet {
+	return detectDotNet(fsys, config, false)
+}
+
+// ExplainDotNet is DetectDotNet, but finds every sign of C# rather than
+// stopping at the first, for reporting why a project was detected as C#.
+func ExplainDotNet(fsys fs.FS, config *Config) DotNet {
+	return detectDotNet(fsys, config, true)
+}
+
+//nolint:cyclop
+func detectDotNet(fsys fs.FS, config *Config, all bool) DotNet {
+	var reasons []string
+
+	found := func() bool { return !all && len(reasons) > 0 }
+
+	if section, ok := config.dotNetSection(); ok {
+		reasons = append(reasons, fmt.Sprintf("project.godot has a [%s] section", section))
+
+		if found() {
+			return DotNet{Reasons: reasons}
+		}
+	}
+
+	var csprojs, slns, resources []string
+
+	walkProject(fsys, func(p string) {
+		switch path.Ext(p) {
+		case ".csproj":
+			csprojs = append(csprojs, p)
+		case ".sln":
+			slns = append(slns, p)
+		case ".tscn", ".tres":
+			resources = append(resources, p)
+		}
+	})
+
+	// sdk records whether each .csproj uses Godot's SDK, for the .sln files.
+	sdk := map[string]bool{}
+
+	for _, p := range csprojs {
+		if sdk[p] = usesGodotSDK(fsys, p); sdk[p] {
+			reasons = append(reasons, p+" uses Godot.NET.Sdk")
+
+			if found() {
+				return DotNet{Reasons: reasons}
+			}
+		}
+	}
+
+	// A .sln only counts through a .csproj found above, so it can't be the
+	// first sign.
+	if all {
+		for _, sln := range slns {
+			for _, p := range slnProjects(fsys, sln) {
+				if sdk[p] {
+					reasons = append(reasons, fmt.Sprintf("%s includes %s", sln, p))
+				}
+			}
+		}
+	}
+
+	for _, p := range resources {
+		if script := csharpScript(fsys, p); script != "" {
+			reasons = append(reasons, fmt.Sprintf("%s uses the C# script %s", p, script))
+
+			if found() {
+				break
+			}
+		}
+	}
+
+	return DotNet{Reasons: reasons}
+}
+
+// walkProject calls fn with the path of each file in a project, skipping .godot,
+// .git and other hidden directories.
+func walkProject(fsys fs.FS, fn func(p string)) {
+	_ = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil //nolint:nilerr
+		}
+
+		if d.IsDir() {
+			return lo.Ternary(p != "." && strings.HasPrefix(d.Name(), "."), fs.SkipDir, nil)
+		}
+
+		fn(p)
+
+		return nil
+	})
+}
+
+// dotNetSection returns the name of the C# section of project.godot, if it
+// has one.
+func (c *Config) dotNetSection() (string, bool) {
+	if c.doc == nil {
+		return "", false
+	}
+
+	return lo.Find(c.doc.Sections(), func(s string) bool { return s == "dotnet" || s == "mono" })
+}
+
+// usesGodotSDK reports whether a .csproj builds against Godot, with the
+// Godot.NET.Sdk of Godot 3.3 and later or, before that, a GodotSharp reference.
+func usesGodotSDK(fsys fs.FS, p string) bool {
+	data, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(string(data), "Godot.NET.Sdk") || strings.Contains(string(data), `Include="GodotSharp"`)
+}
+
+// slnProjects returns the paths of the .csproj files included by a .sln file.
+func slnProjects(fsys fs.FS, sln string) []string {
+	data, err := fs.ReadFile(fsys, sln)
+	if err != nil {
+		return nil
+	}
+
+	return lo.FilterMap(slnProject.FindAllStringSubmatch(string(data), -1), func(m []string, _ int) (string, bool) {
+		// Solutions are written on Windows, with backslashes.
+		p := path.Join(path.Dir(sln), strings.ReplaceAll(m[1], `\`, "/"))
+
+		return p, strings.HasSuffix(p, ".csproj")
+	})
+}
+
+// extResourcePath matches the path of an ext_resource header.
+var extResourcePath = regexp.MustCompile(`\spath="([^"]*)"`) //nolint:gochecknoglobals
+
+// csharpScript returns the first C# script used by a scene or resource. Only
+// the ext_resource headers are read, which Godot writes at the top of the file,
+// one per line.
+func csharpScript(fsys fs.FS, p string) string {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20) //nolint:mnd
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "", strings.HasPrefix(line, "[gd_scene"), strings.HasPrefix(line, "[gd_resource"):
+		case strings.HasPrefix(line, "[ext_resource"):
+			if m := extResourcePath.FindStringSubmatch(line); m != nil && strings.HasSuffix(m[1], ".cs") {
+				return m[1]
+			}
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
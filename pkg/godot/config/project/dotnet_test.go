@@ -0,0 +1,153 @@
+package project_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gdscriptProject = `config_version=5
+
+[application]
+
+config/name="Game"
+config/features=PackedStringArray("4.3")
+`
+
+func parse(t *testing.T, content string) *project.Config {
+	t.Helper()
+
+	config, err := project.Parse([]byte(content))
+	require.NoError(t, err)
+
+	return config
+}
+
+func TestDetectDotNet(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"Game.csproj": {Data: []byte(`<Project Sdk="Godot.NET.Sdk/4.3.0">` + "\n</Project>\n")},
+		"solution/Game.sln": {Data: []byte(heredoc.Doc(`
+			Microsoft Visual Studio Solution File, Format Version 12.00
+			Project("{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}") = "Game", "..\Game.csproj", "{1F9BDE2D-8B0B-4E4B-9F0B-5A3B8C2D7E11}"
+			EndProject
+		`))},
+		"tools/Tool.csproj": {Data: []byte(`<Project Sdk="Microsoft.NET.Sdk">` + "\n</Project>\n")},
+		"player.tscn": {Data: []byte(heredoc.Doc(`
+			[gd_scene load_steps=2 format=3]
+
+			[ext_resource type="Script" path="res://Player.cs" id="1_abcde"]
+
+			[node name="Player" type="CharacterBody2D"]
+			script = ExtResource("1_abcde")
+		`))},
+		"enemy.tscn": {Data: []byte(heredoc.Doc(`
+			[gd_scene load_steps=2 format=3]
+
+			[ext_resource type="Script" path="res://enemy.gd" id="1_abcde"]
+
+			[node name="Enemy" type="Node2D"]
+			script = ExtResource("1_abcde")
+		`))},
+		".godot/mono/Stale.csproj": {Data: []byte(`<Project Sdk="Godot.NET.Sdk/4.2.0">`)},
+	}
+
+	config := parse(t, gdscriptProject+"\n[dotnet]\n\nproject/assembly_name=\"Game\"\n")
+
+	dotnet := project.DetectDotNet(fsys, config)
+
+	assert.True(t, dotnet.Enabled())
+	assert.Equal(t, []string{"project.godot has a [dotnet] section"}, dotnet.Reasons)
+
+	dotnet = project.ExplainDotNet(fsys, config)
+
+	assert.True(t, dotnet.Enabled())
+	assert.Equal(t, []string{
+		"project.godot has a [dotnet] section",
+		"Game.csproj uses Godot.NET.Sdk",
+		"solution/Game.sln includes Game.csproj",
+		"player.tscn uses the C# script res://Player.cs",
+	}, dotnet.Reasons)
+}
+
+func TestDetectDotNet_Signals(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		project string
+		files   fstest.MapFS
+		reasons []string
+	}{
+		{
+			name:    "csproj without section",
+			project: gdscriptProject,
+			files:   fstest.MapFS{"Game.csproj": {Data: []byte(`<Project Sdk="Godot.NET.Sdk/4.3.0"></Project>`)}},
+			reasons: []string{"Game.csproj uses Godot.NET.Sdk"},
+		},
+		{
+			name:    "godot 3 mono section",
+			project: "config_version=4\n\n[mono]\n\nproject/assembly_name=\"Game\"\n",
+			files:   fstest.MapFS{},
+			reasons: []string{"project.godot has a [mono] section"},
+		},
+		{
+			name:    "scripts only",
+			project: gdscriptProject,
+			files: fstest.MapFS{"ui/menu.tscn": {Data: []byte(heredoc.Doc(`
+				[gd_scene load_steps=2 format=2]
+
+				[ext_resource path="res://ui/Menu.cs" type="Script" id=1]
+
+				[node name="Menu" type="Control"]
+				script = ExtResource( 1 )
+			`))}},
+			reasons: []string{"ui/menu.tscn uses the C# script res://ui/Menu.cs"},
+		},
+		{
+			name:    "script in a later ext_resource",
+			project: gdscriptProject,
+			files: fstest.MapFS{"level.tscn": {Data: []byte(heredoc.Doc(`
+				[gd_scene load_steps=3 format=3 uid="uid://b6x3m2k8q1w7e"]
+
+				[ext_resource type="Texture2D" uid="uid://c2v7hr0t4oh5y" path="res://icon.svg" id="1_icon"]
+				[ext_resource type="Script" uid="uid://dq1yw4h6gk3m2" path="res://Level.cs" id="2_level"]
+
+				[node name="Level" type="Node2D"]
+				script = ExtResource("2_level")
+			`))}},
+			reasons: []string{"level.tscn uses the C# script res://Level.cs"},
+		},
+		{
+			name:    "script path outside ext_resource",
+			project: gdscriptProject,
+			files: fstest.MapFS{"level.tscn": {Data: []byte(heredoc.Doc(`
+				[gd_scene format=3]
+
+				[node name="Level" type="Node2D"]
+				path = "res://Level.cs"
+			`))}},
+		},
+		{
+			name:    "unrelated dotnet keys",
+			project: gdscriptProject + "\n[editor_plugins]\n\ndotnet_tools=PackedStringArray()\n",
+			files:   fstest.MapFS{"tools/Tool.csproj": {Data: []byte(`<Project Sdk="Microsoft.NET.Sdk"></Project>`)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dotnet := project.DetectDotNet(tt.files, parse(t, tt.project))
+
+			assert.Equal(t, tt.reasons, dotnet.Reasons)
+			assert.Equal(t, len(tt.reasons) > 0, dotnet.Enabled())
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package project
+
+import (
+	"path"
+	"strings"
+
+	"github.com/ruffel/godotreleaser/pkg/godot/config/document"
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
+	"github.com/samber/lo"
+)
+
+// Autoload is a script or scene that Godot loads when the game starts.
+type Autoload struct {
+	Name string
+	Path string
+	// Singleton reports whether the autoload is available as a global
+	// variable, which project.godot marks with a "*" before the path.
+	Singleton bool
+}
+
+// InputAction is an action of the input map.
+type InputAction struct {
+	Name     string
+	Deadzone float64
+	// Events are the input events that trigger the action, such as
+	// InputEventKey and InputEventJoypadButton objects.
+	Events []variant.Object
+}
+
+// Get returns a setting by its full name, such as "application/config/name",
+// decoded by variant.Parse. Values that can't be decoded are returned as text.
+// Names without a "/", such as "config_version", are read from the top of the
+// file.
+func (c *Config) Get(key string) (any, bool) {
+	if c.doc == nil {
+		return nil, false
+	}
+
+	section, name, ok := strings.Cut(key, "/")
+	if !ok {
+		section, name = document.Global, key
+	}
+
+	raw, ok := c.doc.GetRaw(section, name)
+	if !ok {
+		return nil, false
+	}
+
+	v, err := variant.Parse(raw)
+	if err != nil {
+		return raw, true
+	}
+
+	return v, true
+}
+
+// Autoloads returns the autoloads in the order Godot loads them.
+func (c *Config) Autoloads() []Autoload {
+	return lo.FilterMap(c.keys("autoload"), func(name string, _ int) (Autoload, bool) {
+		value := c.getString("autoload/"+name, "")
+		if value == "" {
+			return Autoload{}, false
+		}
+
+		return Autoload{
+			Name:      name,
+			Path:      strings.TrimPrefix(value, "*"),
+			Singleton: strings.HasPrefix(value, "*"),
+		}, true
+	})
+}
+
+// InputActions returns the actions defined by the project, which don't include
+// Godot's built-in ui_* actions unless the project changed them.
+func (c *Config) InputActions() []InputAction {
+	return lo.FilterMap(c.keys("input"), func(name string, _ int) (InputAction, bool) {
+		v, _ := c.Get("input/" + name)
+
+		action, ok := v.(variant.Dictionary)
+		if !ok {
+			return InputAction{}, false
+		}
+
+		result := InputAction{Name: name, Deadzone: 0.5}
+
+		if deadzone, ok := action.Get("deadzone"); ok {
+			result.Deadzone = toFloat(deadzone, result.Deadzone)
+		}
+
+		if events, ok := action.Get("events"); ok {
+			list, _ := events.([]any)
+
+			result.Events = lo.FilterMap(list, func(e any, _ int) (variant.Object, bool) {
+				o, ok := e.(variant.Object)
+
+				return o, ok
+			})
+		}
+
+		return result, true
+	})
+}
+
+// WindowSize returns the size of the game's viewport, using Godot's defaults
+// for settings that aren't set.
+func (c *Config) WindowSize() (int64, int64) {
+	if c.IsGodot3() {
+		return c.getInt("display/window/size/width", 1024), c.getInt("display/window/size/height", 600)
+	}
+
+	return c.getInt("display/window/size/viewport_width", 1152), c.getInt("display/window/size/viewport_height", 648)
+}
+
+// StretchMode returns display/window/stretch/mode, such as "canvas_items" or,
+// in Godot 3, "2d".
+func (c *Config) StretchMode() string {
+	return c.getString("display/window/stretch/mode", "disabled")
+}
+
+// RenderingMethod returns the renderer used on desktop platforms, such as
+// "forward_plus", "mobile" or "gl_compatibility". Godot 3 projects return the
+// video driver instead, "GLES3" or "GLES2".
+func (c *Config) RenderingMethod() string {
+	if c.IsGodot3() {
+		return c.getString("rendering/quality/driver/driver_name", "GLES3")
+	}
+
+	return c.getString("rendering/renderer/rendering_method", "forward_plus")
+}
+
+// Icon returns the path of the project's icon.
+func (c *Config) Icon() string {
+	return c.getString("application/config/icon", "")
+}
+
+// ApplicationVersion returns application/config/version, the version of the
+// game itself rather than of Godot.
+func (c *Config) ApplicationVersion() string {
+	return c.getString("application/config/version", "")
+}
+
+// EditorPlugins returns the paths of the plugin.cfg files of the enabled
+// editor plugins.
+func (c *Config) EditorPlugins() []string {
+	return c.getStrings("editor_plugins/enabled")
+}
+
+// AssemblyName returns the name of the project's C# assembly. Like Godot, it
+// falls back to the name of the project when it isn't set.
+func (c *Config) AssemblyName() string {
+	section := lo.Ternary(c.IsGodot3(), "mono", "dotnet")
+
+	if name := c.getString(section+"/project/assembly_name", ""); name != "" {
+		return name
+	}
+
+	return lo.Ternary(c.Name != "", c.Name, "UnnamedProject")
+}
+
+// Translations returns the paths of the project's translation files.
+func (c *Config) Translations() []string {
+	if c.IsGodot3() {
+		return c.getStrings("locale/translations")
+	}
+
+	return c.getStrings("internationalization/locale/translations")
+}
+
+// Locales returns the locales of the project's translations, as named by their
+// files: "text.fr.translation" (imported from CSV) and "fr.po" are both "fr".
+func (c *Config) Locales() []string {
+	return lo.Uniq(lo.FilterMap(c.Translations(), func(p string, _ int) (string, bool) {
+		name := strings.TrimSuffix(path.Base(p), path.Ext(p))
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+
+		return name, name != ""
+	}))
+}
+
+func (c *Config) keys(section string) []string {
+	if c.doc == nil {
+		return nil
+	}
+
+	return c.doc.Keys(section)
+}
+
+func (c *Config) getString(key, fallback string) string {
+	v, _ := c.Get(key)
+
+	switch v := v.(type) {
+	case string:
+		return v
+	case variant.StringName:
+		return string(v)
+	}
+
+	return fallback
+}
+
+func (c *Config) getInt(key string, fallback int64) int64 {
+	v, _ := c.Get(key)
+
+	if n, ok := v.(int64); ok {
+		return n
+	}
+
+	return fallback
+}
+
+func (c *Config) getStrings(key string) []string {
+	v, _ := c.Get(key)
+
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []any:
+		return lo.FilterMap(v, func(item any, _ int) (string, bool) {
+			s, ok := item.(string)
+
+			return s, ok
+		})
+	}
+
+	return nil
+}
+
+func toFloat(v any, fallback float64) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	}
+
+	return fallback
+}
@@ -0,0 +1,149 @@
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/samber/lo"
+)
+
+// Files that pin the version of Godot a project is built with.
+const (
+	// GodotVersionFile holds just the version, such as "4.3.1".
+	GodotVersionFile = ".godot-version"
+	// ToolVersionsFile is asdf's list of tool versions, with a "godot 4.3.1"
+	// line.
+	ToolVersionsFile = ".tool-versions"
+)
+
+// sdkVersion matches the version of the Godot.NET.Sdk used by a .csproj.
+var sdkVersion = regexp.MustCompile(`Godot\.NET\.Sdk/([0-9][^"'\s<]*)`) //nolint:gochecknoglobals
+
+// VersionSource is a version of Godot asked for by a project.
+type VersionSource struct {
+	// Source names where the version was found, such as ".godot-version".
+	Source string
+	// Version is in the form used by the official downloads, for example
+	// "4.3" or "4.2.2".
+	Version string
+	// Exact is false for versions that only give the minor version, such as
+	// those in the features list of project.godot.
+	Exact bool
+}
+
+// Agrees reports whether two sources ask for the same version. An inexact
+// version agrees with every patch release of its minor version.
+func (v VersionSource) Agrees(other VersionSource) bool {
+	if v.Version == other.Version {
+		return true
+	}
+
+	inexact, exact := v, other
+	if v.Exact {
+		inexact, exact = other, v
+	}
+
+	return !inexact.Exact && strings.HasPrefix(exact.Version, inexact.Version+".")
+}
+
+// EngineVersions returns the versions of Godot found in a project, most
+// trusted first: .godot-version, .tool-versions, the Godot.NET.Sdk of each
+// .csproj, and the features list of project.godot. fsys is the directory of the
+// project. Pre-releases are skipped, as only stable releases can be downloaded.
+func (c *Config) EngineVersions(fsys fs.FS) []VersionSource {
+	var sources []VersionSource
+
+	add := func(source, raw string, exact bool) {
+		if v, ok := normalizeVersion(raw); ok {
+			sources = append(sources, VersionSource{Source: source, Version: v, Exact: exact})
+		}
+	}
+
+	if data, err := fs.ReadFile(fsys, GodotVersionFile); err == nil {
+		add(GodotVersionFile, string(data), true)
+	}
+
+	if data, err := fs.ReadFile(fsys, ToolVersionsFile); err == nil {
+		if v, ok := toolVersion(data, "godot"); ok {
+			add(ToolVersionsFile, v, true)
+		}
+	}
+
+	walkProject(fsys, func(p string) {
+		if path.Ext(p) != ".csproj" {
+			return
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return
+		}
+
+		if m := sdkVersion.FindSubmatch(data); m != nil {
+			add(p, string(m[1]), true)
+		}
+	})
+
+	if v := c.EngineVersion(); v != nil {
+		add("project.godot", v.Original(), false)
+	}
+
+	return sources
+}
+
+// ResolveVersion returns the first of a list of sources, ordered by trust, and
+// the sources that disagree with it. It returns false if the list is empty.
+func ResolveVersion(sources []VersionSource) (VersionSource, []VersionSource, bool) {
+	if len(sources) == 0 {
+		return VersionSource{}, nil, false
+	}
+
+	conflicts := lo.Reject(sources[1:], func(s VersionSource, _ int) bool { return s.Agrees(sources[0]) })
+
+	return sources[0], conflicts, true
+}
+
+// normalizeVersion converts a stable version such as "v4.3.0-stable" to the
+// form used by the official downloads, "4.3".
+func normalizeVersion(raw string) (string, bool) {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "v")
+	raw = strings.TrimSuffix(strings.TrimSuffix(raw, "-stable"), ".stable")
+
+	v, err := version.NewVersion(raw)
+	if err != nil || v.Prerelease() != "" || v.Metadata() != "" {
+		return "", false
+	}
+
+	parts := strings.Split(raw, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", false
+	}
+
+	// Godot x.y.0 releases are named x.y.
+	if len(parts) == 3 && parts[2] == "0" {
+		parts = parts[:2]
+	}
+
+	return strings.Join(parts, "."), true
+}
+
+// toolVersion returns the first version of a tool in a .tool-versions file.
+func toolVersion(data []byte, tool string) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == tool {
+			return fields[1], true
+		}
+	}
+
+	return "", false
+}
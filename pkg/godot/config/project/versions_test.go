@@ -0,0 +1,71 @@
+package project_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/project"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_EngineVersions(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		".godot-version": {Data: []byte("v4.3.1-stable\n")},
+		".tool-versions": {Data: []byte(heredoc.Doc(`
+			# Pinned for CI
+			nodejs 20.11.0
+			godot 4.3.0 4.2.2
+		`))},
+		"Game.csproj":            {Data: []byte(`<Project Sdk="Godot.NET.Sdk/4.3.1">` + "\n</Project>\n")},
+		"beta/Beta.csproj":       {Data: []byte(`<Project Sdk="Godot.NET.Sdk/4.4.0-beta.1">`)},
+		".godot/mono/Old.csproj": {Data: []byte(`<Project Sdk="Godot.NET.Sdk/4.2.0">`)},
+	}
+
+	assert.Equal(t, []project.VersionSource{
+		{Source: ".godot-version", Version: "4.3.1", Exact: true},
+		{Source: ".tool-versions", Version: "4.3", Exact: true},
+		{Source: "Game.csproj", Version: "4.3.1", Exact: true},
+		{Source: "project.godot", Version: "4.3", Exact: false},
+	}, parse(t, gdscriptProject).EngineVersions(fsys))
+
+	assert.Empty(t, parse(t, "config_version=4\n").EngineVersions(fstest.MapFS{}))
+}
+
+func TestResolveVersion(t *testing.T) {
+	t.Parallel()
+
+	features := project.VersionSource{Source: "project.godot", Version: "4.3"}
+	sdk := project.VersionSource{Source: "Game.csproj", Version: "4.3.1", Exact: true}
+	pinned := project.VersionSource{Source: ".godot-version", Version: "4.3", Exact: true}
+	other := project.VersionSource{Source: ".tool-versions", Version: "4.2.2", Exact: true}
+
+	tests := []struct {
+		name      string
+		sources   []project.VersionSource
+		chosen    project.VersionSource
+		conflicts []project.VersionSource
+	}{
+		{name: "sdk refines features", sources: []project.VersionSource{sdk, features}, chosen: sdk, conflicts: []project.VersionSource{}},
+		{name: "features alone", sources: []project.VersionSource{features}, chosen: features, conflicts: []project.VersionSource{}},
+		{name: "exact versions differ", sources: []project.VersionSource{pinned, sdk, features}, chosen: pinned, conflicts: []project.VersionSource{sdk}},
+		{name: "minor differs", sources: []project.VersionSource{other, features}, chosen: other, conflicts: []project.VersionSource{features}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			chosen, conflicts, ok := project.ResolveVersion(tt.sources)
+
+			assert.True(t, ok)
+			assert.Equal(t, tt.chosen, chosen)
+			assert.Equal(t, tt.conflicts, conflicts)
+		})
+	}
+
+	_, _, ok := project.ResolveVersion(nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,205 @@
+package resource
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
+	"github.com/samber/lo"
+)
+
+// formatGodot3 is the format of files written by Godot 3.
+const formatGodot3 = 2
+
+// Bytes renders the file as Godot writes it: in Godot 3's syntax for files
+// read from Godot 3 (format=2), and Godot 4's otherwise.
+//
+//nolint:cyclop,funlen
+func (f *File) Bytes() []byte {
+	r := &renderer{godot3: f.Format == formatGodot3}
+
+	header := Properties{}
+	header = appendStr(header, "type", f.Type)
+	header = appendStr(header, "script_class", f.ScriptClass)
+	header = appendInt(header, "load_steps", f.LoadSteps)
+	header = appendInt(header, "format", f.Format)
+	header = appendStr(header, "uid", f.UID)
+
+	r.header(string(f.Kind), header)
+
+	if len(f.ExtResources) > 0 {
+		r.b.WriteString("\n")
+	}
+
+	for _, res := range f.ExtResources {
+		var attrs Properties
+
+		// Godot 3 puts the path first.
+		if r.godot3 {
+			attrs = appendStr(attrs, "path", res.Path)
+			attrs = appendStr(attrs, "type", res.Type)
+		} else {
+			attrs = appendStr(attrs, "type", res.Type)
+			attrs = appendStr(attrs, "uid", res.UID)
+			attrs = appendStr(attrs, "path", res.Path)
+		}
+
+		attrs = append(attrs, r.id(res.ID))
+
+		r.header("ext_resource", attrs)
+	}
+
+	for _, res := range f.SubResources {
+		attrs := appendStr(nil, "type", res.Type)
+		attrs = append(attrs, r.id(res.ID))
+
+		r.b.WriteString("\n")
+		r.header("sub_resource", attrs)
+		r.properties(res.Properties)
+	}
+
+	if f.Kind == Resource {
+		r.b.WriteString("\n")
+		r.header("resource", nil)
+		r.properties(f.Properties)
+	}
+
+	for _, n := range f.Nodes {
+		r.b.WriteString("\n")
+		r.header("node", r.nodeAttributes(n))
+		r.properties(n.Properties)
+	}
+
+	if len(f.Connections) > 0 {
+		r.b.WriteString("\n")
+	}
+
+	for _, c := range f.Connections {
+		attrs := appendStr(nil, "signal", c.Signal)
+		attrs = appendStr(attrs, "from", c.From)
+		attrs = appendStr(attrs, "to", c.To)
+		attrs = appendStr(attrs, "method", c.Method)
+		attrs = appendInt(attrs, "flags", c.Flags)
+
+		if len(c.Binds) > 0 {
+			// Godot 3 leaves a space after the equals sign.
+			attrs = append(attrs, Property{Name: "binds", Value: lo.Ternary[any](r.godot3, raw(" "+r.format(c.Binds)), c.Binds)})
+		}
+
+		attrs = appendInt(attrs, "unbinds", c.Unbinds)
+
+		r.header("connection", attrs)
+	}
+
+	if len(f.Editable) > 0 {
+		r.b.WriteString("\n")
+	}
+
+	for _, path := range f.Editable {
+		r.header("editable", appendStr(nil, "path", path))
+	}
+
+	return []byte(r.b.String())
+}
+
+// Save writes the file, keeping its permissions if it already exists.
+func (f *File) Save(path string) error {
+	mode := os.FileMode(0o644)
+
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := os.WriteFile(path, f.Bytes(), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// renderer writes the sections of a file in the syntax of one major version
+// of Godot.
+type renderer struct {
+	b      strings.Builder
+	godot3 bool
+}
+
+// raw is an attribute value that is written as it is.
+type raw string
+
+func (r *renderer) format(v any) string {
+	if v, ok := v.(raw); ok {
+		return string(v)
+	}
+
+	return lo.Ternary(r.godot3, variant.FormatGodot3, variant.Format)(v)
+}
+
+// id returns the id attribute of a resource. Godot 3 numbers resources, and
+// writes the ids as integers.
+func (r *renderer) id(id string) Property {
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil && r.godot3 {
+		return Property{Name: "id", Value: n}
+	}
+
+	return Property{Name: "id", Value: id}
+}
+
+func (r *renderer) nodeAttributes(n Node) Properties {
+	attrs := appendStr(nil, "name", n.Name)
+	attrs = appendStr(attrs, "type", n.Type)
+	attrs = appendStr(attrs, "parent", n.Parent)
+	attrs = append(attrs, n.Attributes...)
+
+	// Godot 3 writes the groups before the instance, one per line.
+	if r.godot3 && len(n.Groups) > 0 {
+		groups := lo.Map(n.Groups, func(g string, _ int) string { return variant.Format(g) + ",\n" })
+		attrs = append(attrs, Property{Name: "groups", Value: raw("[\n" + strings.Join(groups, "") + "]")})
+	}
+
+	attrs = appendStr(attrs, "instance_placeholder", n.InstancePlaceholder)
+
+	if n.Instance != "" {
+		attrs = append(attrs, Property{Name: "instance", Value: variant.ExtResource{ID: n.Instance}})
+	}
+
+	if !r.godot3 && len(n.Groups) > 0 {
+		attrs = append(attrs, Property{Name: "groups", Value: lo.ToAnySlice(n.Groups)})
+	}
+
+	return attrs
+}
+
+func (r *renderer) header(tag string, attrs Properties) {
+	r.b.WriteString("[" + tag)
+
+	for _, a := range attrs {
+		r.b.WriteString(" " + a.Name + "=" + r.format(a.Value))
+	}
+
+	r.b.WriteString("]\n")
+}
+
+func (r *renderer) properties(props Properties) {
+	for _, p := range props {
+		r.b.WriteString(p.Name + " = " + r.format(p.Value) + "\n")
+	}
+}
+
+func appendStr(attrs Properties, name, value string) Properties {
+	if value == "" {
+		return attrs
+	}
+
+	return append(attrs, Property{Name: name, Value: value})
+}
+
+func appendInt(attrs Properties, name string, value int64) Properties {
+	if value == 0 {
+		return attrs
+	}
+
+	return append(attrs, Property{Name: name, Value: value})
+}
@@ -0,0 +1,384 @@
+// Package resource reads and writes Godot's text scenes (.tscn) and resources
+// (.tres).
+//
+// Both are made of sections whose headers carry attributes, such as
+// [node name="Player" type="CharacterBody2D"], followed by property values in
+// the format described by the variant package. Scenes and resources written by
+// Godot 3 (format=2) and Godot 4 (format=3) can both be read, and are written
+// back in the syntax of the same version.
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ruffel/godotreleaser/pkg/godot/config/document"
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
+)
+
+// ErrSyntax is wrapped by the errors returned for files that cannot be parsed.
+var ErrSyntax = errors.New("invalid resource file")
+
+// Kind is the kind of a file, given by the tag of its first section.
+type Kind string
+
+const (
+	Scene    Kind = "gd_scene"
+	Resource Kind = "gd_resource"
+)
+
+// Property is a named value, such as an attribute of a section header or a
+// property of a node.
+type Property struct {
+	Name  string
+	Value any
+}
+
+// Properties is a list of properties in the order they are written.
+type Properties []Property
+
+// Get returns the value of a property.
+func (p Properties) Get(name string) (any, bool) {
+	for _, prop := range p {
+		if prop.Name == name {
+			return prop.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// File is a parsed scene or resource.
+type File struct {
+	Kind Kind
+	// Type is the class of a resource, and empty for scenes.
+	Type string
+	// ScriptClass is the global class name of a resource's script, if any.
+	ScriptClass string
+	LoadSteps   int64
+	Format      int64
+	UID         string
+
+	ExtResources []ExtResource
+	SubResources []SubResource
+	// Properties are those of the [resource] section of a resource.
+	Properties Properties
+	Nodes      []Node
+	// Connections are the signal connections of a scene.
+	Connections []Connection
+	// Editable are the paths of instanced scenes whose children can be edited.
+	Editable []string
+}
+
+// ExtResource is a resource loaded from another file.
+type ExtResource struct {
+	Type string
+	UID  string
+	Path string
+	ID   string
+}
+
+// SubResource is a resource embedded in the file.
+type SubResource struct {
+	Type       string
+	ID         string
+	Properties Properties
+}
+
+// Node is a node of a scene.
+type Node struct {
+	Name string
+	Type string
+	// Parent is the path of the parent node, relative to the root node. It is
+	// empty for the root node, and "." for its children.
+	Parent string
+	// Instance is the ID of the ExtResource of an instanced scene.
+	Instance string
+	// InstancePlaceholder is the path of a scene that is loaded at runtime.
+	InstancePlaceholder string
+	Groups              []string
+	// Attributes are any other attributes of the section header, such as
+	// index or owner.
+	Attributes Properties
+	Properties Properties
+}
+
+// Path returns the path of the node relative to the root node, which is ".".
+func (n Node) Path() string {
+	switch n.Parent {
+	case "":
+		return "."
+	case ".":
+		return n.Name
+	default:
+		return n.Parent + "/" + n.Name
+	}
+}
+
+// Connection is a signal connection between two nodes of a scene.
+type Connection struct {
+	Signal string
+	From   string
+	To     string
+	Method string
+	Flags  int64
+	// Binds are extra arguments passed to the method, as written by Godot 3.
+	Binds []any
+	// Unbinds is the number of signal arguments dropped, as written by Godot 4.
+	Unbinds int64
+}
+
+// ExtResource returns the external resource with the given ID.
+func (f *File) ExtResource(id string) (ExtResource, bool) {
+	for _, r := range f.ExtResources {
+		if r.ID == id {
+			return r, true
+		}
+	}
+
+	return ExtResource{}, false
+}
+
+// SubResource returns the embedded resource with the given ID.
+func (f *File) SubResource(id string) (SubResource, bool) {
+	for _, r := range f.SubResources {
+		if r.ID == id {
+			return r, true
+		}
+	}
+
+	return SubResource{}, false
+}
+
+// Load reads and parses a scene or resource file.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// Parse parses the contents of a scene or resource file.
+func Parse(data []byte) (*File, error) {
+	doc, err := document.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSyntax, err)
+	}
+
+	raw := doc.Raw()
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: empty file", ErrSyntax)
+	}
+
+	f := &File{}
+
+	for i, s := range raw {
+		tag, attrs, err := parseHeader(s.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		props, err := parseProperties(s)
+		if err != nil {
+			return nil, fmt.Errorf("[%s]: %w", tag, err)
+		}
+
+		if i == 0 {
+			if tag != string(Scene) && tag != string(Resource) {
+				return nil, fmt.Errorf("%w: expected gd_scene or gd_resource, got [%s]", ErrSyntax, tag)
+			}
+
+			f.Kind = Kind(tag)
+			f.Type = str(attrs, "type")
+			f.ScriptClass = str(attrs, "script_class")
+			f.LoadSteps = integer(attrs, "load_steps")
+			f.Format = integer(attrs, "format")
+			f.UID = str(attrs, "uid")
+
+			continue
+		}
+
+		if err := f.add(tag, attrs, props); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+func (f *File) add(tag string, attrs, props Properties) error {
+	switch tag {
+	case "ext_resource":
+		f.ExtResources = append(f.ExtResources, ExtResource{
+			Type: str(attrs, "type"),
+			UID:  str(attrs, "uid"),
+			Path: str(attrs, "path"),
+			ID:   id(attrs, "id"),
+		})
+	case "sub_resource":
+		f.SubResources = append(f.SubResources, SubResource{
+			Type:       str(attrs, "type"),
+			ID:         id(attrs, "id"),
+			Properties: props,
+		})
+	case "resource":
+		f.Properties = props
+	case "node":
+		f.Nodes = append(f.Nodes, parseNode(attrs, props))
+	case "connection":
+		binds, _ := get(attrs, "binds").([]any)
+
+		f.Connections = append(f.Connections, Connection{
+			Signal:  str(attrs, "signal"),
+			From:    str(attrs, "from"),
+			To:      str(attrs, "to"),
+			Method:  str(attrs, "method"),
+			Flags:   integer(attrs, "flags"),
+			Binds:   binds,
+			Unbinds: integer(attrs, "unbinds"),
+		})
+	case "editable":
+		f.Editable = append(f.Editable, str(attrs, "path"))
+	default:
+		return fmt.Errorf("%w: unknown section [%s]", ErrSyntax, tag)
+	}
+
+	return nil
+}
+
+func parseNode(attrs, props Properties) Node {
+	n := Node{Properties: props}
+
+	for _, a := range attrs {
+		switch a.Name {
+		case "name":
+			n.Name = str(attrs, a.Name)
+		case "type":
+			n.Type = str(attrs, a.Name)
+		case "parent":
+			n.Parent = str(attrs, a.Name)
+		case "instance":
+			n.Instance = id(attrs, a.Name)
+		case "instance_placeholder":
+			n.InstancePlaceholder = str(attrs, a.Name)
+		case "groups":
+			n.Groups = strs(a.Value)
+		default:
+			n.Attributes = append(n.Attributes, a)
+		}
+	}
+
+	return n
+}
+
+// parseHeader splits the text of a section header, such as
+// `node name="Player" parent="."`, into its tag and attributes.
+func parseHeader(text string) (string, Properties, error) {
+	tag, rest, _ := strings.Cut(text, " ")
+
+	var attrs Properties
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		name, value, ok := strings.Cut(rest, "=")
+		if !ok || strings.ContainsAny(name, " \t") {
+			return "", nil, fmt.Errorf("%w: [%s]: expected name=value, got %q", ErrSyntax, tag, rest)
+		}
+
+		v, n, err := variant.ParsePrefix(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: [%s] %s: %w", ErrSyntax, tag, name, err)
+		}
+
+		attrs = append(attrs, Property{Name: name, Value: v})
+		rest = value[n:]
+	}
+
+	return tag, attrs, nil
+}
+
+func parseProperties(s document.RawSection) (Properties, error) {
+	props := make(Properties, 0, len(s.Keys))
+
+	for i, key := range s.Keys {
+		v, err := variant.Parse(s.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrSyntax, key, err)
+		}
+
+		props = append(props, Property{Name: key, Value: v})
+	}
+
+	return props, nil
+}
+
+func get(attrs Properties, name string) any {
+	v, _ := attrs.Get(name)
+
+	return v
+}
+
+func str(attrs Properties, name string) string {
+	switch v := get(attrs, name).(type) {
+	case string:
+		return v
+	case variant.StringName:
+		return string(v)
+	case variant.NodePath:
+		return string(v)
+	}
+
+	return ""
+}
+
+func integer(attrs Properties, name string) int64 {
+	n, _ := get(attrs, name).(int64)
+
+	return n
+}
+
+// id returns a resource ID, which Godot 3 writes as an integer and Godot 4 as
+// a string. References such as instance=ExtResource("1") are resolved to the
+// ID they refer to.
+func id(attrs Properties, name string) string {
+	switch v := get(attrs, name).(type) {
+	case string:
+		return v
+	case int64:
+		return fmt.Sprint(v)
+	case variant.ExtResource:
+		return v.ID
+	case variant.SubResource:
+		return v.ID
+	}
+
+	return ""
+}
+
+func strs(v any) []string {
+	var out []string
+
+	switch v := v.(type) {
+	case []string:
+		out = v
+	case []any:
+		for _, item := range v {
+			switch s := item.(type) {
+			case string:
+				out = append(out, s)
+			case variant.StringName:
+				out = append(out, string(s))
+			}
+		}
+	}
+
+	return out
+}
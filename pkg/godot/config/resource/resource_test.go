@@ -0,0 +1,231 @@
+package resource_test
+
+import (
+	"testing"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/ruffel/godotreleaser/pkg/godot/config/resource"
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var scene4 = heredoc.Doc(`
+	[gd_scene load_steps=4 format=3 uid="uid://bq7x1ngsm6h3k"]
+
+	[ext_resource type="Script" path="res://player.gd" id="1_ykr0s"]
+	[ext_resource type="PackedScene" uid="uid://c2o4x1ww0ymr7" path="res://enemy.tscn" id="2_hx5lm"]
+
+	[sub_resource type="RectangleShape2D" id="RectangleShape2D_6ewfc"]
+	size = Vector2(20, 32)
+
+	[node name="Main" type="Node2D"]
+
+	[node name="Player" type="CharacterBody2D" parent="." groups=["players"]]
+	script = ExtResource("1_ykr0s")
+	speed = 120.5
+
+	[node name="Shape" type="CollisionShape2D" parent="Player"]
+	shape = SubResource("RectangleShape2D_6ewfc")
+
+	[node name="Enemy" parent="." instance=ExtResource("2_hx5lm")]
+	position = Vector2(64, 0)
+
+	[connection signal="body_entered" from="Enemy" to="Player" method="_on_enemy_body_entered"]
+
+	[editable path="Enemy"]
+`)
+
+func TestParse_Scene(t *testing.T) {
+	t.Parallel()
+
+	f, err := resource.Parse([]byte(scene4))
+	require.NoError(t, err)
+
+	assert.Equal(t, resource.Scene, f.Kind)
+	assert.Equal(t, int64(4), f.LoadSteps)
+	assert.Equal(t, int64(3), f.Format)
+	assert.Equal(t, "uid://bq7x1ngsm6h3k", f.UID)
+
+	require.Len(t, f.ExtResources, 2)
+	assert.Equal(t, resource.ExtResource{
+		Type: "PackedScene",
+		UID:  "uid://c2o4x1ww0ymr7",
+		Path: "res://enemy.tscn",
+		ID:   "2_hx5lm",
+	}, f.ExtResources[1])
+
+	require.Len(t, f.SubResources, 1)
+	assert.Equal(t, resource.Properties{{Name: "size", Value: variant.Vector2{X: 20, Y: 32}}}, f.SubResources[0].Properties)
+
+	require.Len(t, f.Nodes, 4)
+	assert.Equal(t, ".", f.Nodes[0].Path())
+	assert.Equal(t, "Player/Shape", f.Nodes[2].Path())
+	assert.Equal(t, []string{"players"}, f.Nodes[1].Groups)
+
+	script, ok := f.Nodes[1].Properties.Get("script")
+	require.True(t, ok)
+	assert.Equal(t, variant.ExtResource{ID: "1_ykr0s"}, script)
+
+	enemy := f.Nodes[3]
+	assert.Equal(t, "2_hx5lm", enemy.Instance)
+
+	instanced, ok := f.ExtResource(enemy.Instance)
+	require.True(t, ok)
+	assert.Equal(t, "res://enemy.tscn", instanced.Path)
+
+	assert.Equal(t, []resource.Connection{{
+		Signal: "body_entered",
+		From:   "Enemy",
+		To:     "Player",
+		Method: "_on_enemy_body_entered",
+	}}, f.Connections)
+	assert.Equal(t, []string{"Enemy"}, f.Editable)
+}
+
+func TestBytes_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	tres := heredoc.Doc(`
+		[gd_resource type="Theme" load_steps=2 format=3 uid="uid://d1ybd3b7qbh5a"]
+
+		[ext_resource type="FontFile" path="res://fonts/main.ttf" id="1_font"]
+
+		[resource]
+		default_font = ExtResource("1_font")
+		default_font_size = 18
+	`)
+
+	// Each packed array keeps its type, and byte arrays their form: Godot 4.0
+	// to 4.2 can't read base64.
+	arrays := heredoc.Doc(`
+		[gd_resource type="Resource" format=3]
+
+		[resource]
+		int32 = PackedInt32Array(1, -2, 3)
+		int64 = PackedInt64Array(1, -2, 3)
+		float32 = PackedFloat32Array(0, 0.5, 0.1)
+		float64 = PackedFloat64Array(0, 0.5, 0.1)
+		bytes = PackedByteArray(1, 2, 3)
+		base64 = PackedByteArray("AQID")
+		strings = PackedStringArray("a", "b")
+		vector2 = PackedVector2Array(0, 0, 1, 2)
+		vector3 = PackedVector3Array(1, 2, 3)
+		vector4 = PackedVector4Array(1, 2, 3, 4)
+		colors = PackedColorArray(1, 0.5, 0, 1)
+	`)
+
+	for _, input := range []string{scene4, tres, arrays} {
+		f, err := resource.Parse([]byte(input))
+		require.NoError(t, err)
+		assert.Equal(t, input, string(f.Bytes()))
+	}
+}
+
+func TestBytes_RoundTripGodot3(t *testing.T) {
+	t.Parallel()
+
+	scene := heredoc.Doc(`
+		[gd_scene load_steps=4 format=2]
+
+		[ext_resource path="res://Player.gd" type="Script" id=1]
+		[ext_resource path="res://Enemy.tscn" type="PackedScene" id=2]
+
+		[sub_resource type="CircleShape2D" id=1]
+		radius = 16.0
+
+		[node name="Main" type="Node2D" groups=[
+		"level",
+		]]
+		script = ExtResource( 1 )
+		points = PoolVector2Array( 0, 0, 10, 20 )
+		target = NodePath("Enemy")
+
+		[node name="Enemy" parent="." instance=ExtResource( 2 )]
+		position = Vector2( 10, 20 )
+		shape = SubResource( 1 )
+
+		[connection signal="hit" from="Enemy" to="." method="_on_hit" flags=3 binds= [ 1 ]]
+	`)
+
+	tres := heredoc.Doc(`
+		[gd_resource type="Theme" load_steps=2 format=2]
+
+		[ext_resource path="res://fonts/main.tres" type="DynamicFont" id=1]
+
+		[resource]
+		default_font = ExtResource( 1 )
+		names = PoolStringArray( "a", "b" )
+		ints = PoolIntArray( 1, -2 )
+		reals = PoolRealArray( 0.5, 1 )
+		bytes = PoolByteArray( 1, 2, 3 )
+		points = PoolVector3Array( 1, 2, 3 )
+		colors = PoolColorArray( 1, 0.5, 0, 1 )
+		data = {
+
+		}
+	`)
+
+	for _, input := range []string{scene, tres} {
+		f, err := resource.Parse([]byte(input))
+		require.NoError(t, err)
+		assert.Equal(t, input, string(f.Bytes()))
+	}
+}
+
+func TestParse_Godot3(t *testing.T) {
+	t.Parallel()
+
+	f, err := resource.Parse([]byte(heredoc.Doc(`
+		[gd_scene load_steps=3 format=2]
+
+		[ext_resource path="res://Player.gd" type="Script" id=1]
+		[ext_resource path="res://Enemy.tscn" type="PackedScene" id=2]
+
+		[sub_resource type="CircleShape2D" id=1]
+		radius = 16.0
+
+		[node name="Main" type="Node2D"]
+		script = ExtResource( 1 )
+
+		[node name="Enemy" parent="." instance=ExtResource( 2 )]
+		position = Vector2( 10, 20 )
+		shape = SubResource( 1 )
+
+		[node name="Label" type="Label" parent="Enemy" index="0"]
+		text = "Hello"
+
+		[connection signal="hit" from="Enemy" to="." method="_on_hit" binds=[ 1 ] flags=3]
+	`)))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), f.Format)
+	assert.Equal(t, "1", f.ExtResources[0].ID)
+	assert.Equal(t, "1", f.SubResources[0].ID)
+	assert.Equal(t, "2", f.Nodes[1].Instance)
+	assert.Equal(t, resource.Properties{{Name: "index", Value: "0"}}, f.Nodes[2].Attributes)
+	assert.Equal(t, "Enemy/Label", f.Nodes[2].Path())
+
+	position, ok := f.Nodes[1].Properties.Get("position")
+	require.True(t, ok)
+	assert.Equal(t, variant.Vector2{X: 10, Y: 20}, position)
+
+	require.Len(t, f.Connections, 1)
+	assert.Equal(t, int64(3), f.Connections[0].Flags)
+	assert.Equal(t, []any{int64(1)}, f.Connections[0].Binds)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		``,
+		"[node name=\"Main\"]\n",
+		"[gd_scene format=3]\n\n[node name]\n",
+		"[gd_scene format=3]\n\n[node name=\"Main\"]\nposition = Vector2(1\n",
+		"[gd_scene format=3]\n\n[bogus]\n",
+	} {
+		_, err := resource.Parse([]byte(input))
+		assert.ErrorIs(t, err, resource.ErrSyntax, input)
+	}
+}
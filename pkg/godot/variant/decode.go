@@ -0,0 +1,670 @@
+package variant
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrSyntax is wrapped by the errors returned for text that is not a valid
+// value.
+var ErrSyntax = errors.New("invalid variant")
+
+// Parse decodes the text form of a single value.
+func Parse(text string) (any, error) {
+	d := &decoder{s: text}
+
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+
+	d.skipSpace()
+
+	if d.pos < len(d.s) {
+		return nil, d.errorf("unexpected %q after value", d.s[d.pos:])
+	}
+
+	return v, nil
+}
+
+// ParsePrefix decodes the value at the start of text, and returns the number
+// of bytes it used. Leading whitespace is skipped.
+func ParsePrefix(text string) (any, int, error) {
+	d := &decoder{s: text}
+
+	v, err := d.value()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return v, d.pos, nil
+}
+
+type decoder struct {
+	s   string
+	pos int
+}
+
+func (d *decoder) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrSyntax, d.pos, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and comments, which run from ";" or "#" to the
+// end of the line.
+func (d *decoder) skipSpace() {
+	for d.pos < len(d.s) {
+		switch d.s[d.pos] {
+		case ' ', '\t', '\r', '\n':
+			d.pos++
+		case ';', '#':
+			if end := strings.IndexByte(d.s[d.pos:], '\n'); end >= 0 {
+				d.pos += end
+			} else {
+				d.pos = len(d.s)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next character that isn't whitespace, or 0 at the end.
+func (d *decoder) peek() byte {
+	d.skipSpace()
+
+	if d.pos >= len(d.s) {
+		return 0
+	}
+
+	return d.s[d.pos]
+}
+
+func (d *decoder) expect(c byte) error {
+	if d.peek() != c {
+		if d.pos >= len(d.s) {
+			return d.errorf("expected %q, got end of input", c)
+		}
+
+		return d.errorf("expected %q, got %q", c, d.s[d.pos])
+	}
+
+	d.pos++
+
+	return nil
+}
+
+//nolint:cyclop
+func (d *decoder) value() (any, error) {
+	c := d.peek()
+
+	switch {
+	case c == 0:
+		return nil, d.errorf("unexpected end of input")
+	case c == '"':
+		return d.string()
+	case c == '&':
+		d.pos++
+		s, err := d.string()
+
+		return StringName(s), err
+	case c == '^':
+		d.pos++
+		s, err := d.string()
+
+		return NodePath(s), err
+	case c == '[':
+		d.pos++
+
+		return d.array()
+	case c == '{':
+		d.pos++
+
+		return d.dictionary()
+	case c == '-' || c == '+' || c == '.' || isDigit(c):
+		return d.number()
+	case isIdentStart(c):
+		return d.identifier()
+	}
+
+	return nil, d.errorf("unexpected %q", c)
+}
+
+func (d *decoder) string() (string, error) {
+	if err := d.expect('"'); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	for d.pos < len(d.s) {
+		c := d.s[d.pos]
+		d.pos++
+
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if err := d.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", d.errorf("unterminated string")
+}
+
+func (d *decoder) escape(b *strings.Builder) error {
+	if d.pos >= len(d.s) {
+		return d.errorf("unterminated string")
+	}
+
+	c := d.s[d.pos]
+	d.pos++
+
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'u', 'U':
+		r, err := d.codepoint(ternary(c == 'u', 4, 6))
+		if err != nil {
+			return err
+		}
+
+		// Characters outside the BMP may be written as UTF-16 surrogates.
+		if utf16.IsSurrogate(r) && strings.HasPrefix(d.s[d.pos:], `\u`) {
+			d.pos += 2
+
+			low, err := d.codepoint(4)
+			if err != nil {
+				return err
+			}
+
+			r = utf16.DecodeRune(r, low)
+		}
+
+		b.WriteRune(r)
+	default:
+		// Quotes, backslashes and anything else stand for themselves.
+		b.WriteByte(c)
+	}
+
+	return nil
+}
+
+func (d *decoder) codepoint(digits int) (rune, error) {
+	if d.pos+digits > len(d.s) {
+		return 0, d.errorf("truncated unicode escape")
+	}
+
+	n, err := strconv.ParseUint(d.s[d.pos:d.pos+digits], 16, 32)
+	if err != nil {
+		return 0, d.errorf("invalid unicode escape %q", d.s[d.pos:d.pos+digits])
+	}
+
+	d.pos += digits
+
+	return rune(n), nil
+}
+
+func (d *decoder) number() (any, error) {
+	start := d.pos
+
+	if c := d.s[d.pos]; c == '-' || c == '+' {
+		d.pos++
+
+		// Godot 3 writes negative infinity as -inf.
+		if strings.HasPrefix(d.s[d.pos:], "inf") {
+			d.pos += len("inf")
+
+			return ternary(c == '-', math.Inf(-1), math.Inf(1)), nil
+		}
+	}
+
+	float := false
+
+	for d.pos < len(d.s) {
+		c := d.s[d.pos]
+
+		switch {
+		case isDigit(c):
+		case c == '.' || c == 'e' || c == 'E':
+			float = true
+		case (c == '-' || c == '+') && (d.s[d.pos-1] == 'e' || d.s[d.pos-1] == 'E'):
+		default:
+			return d.parseNumber(d.s[start:d.pos], float)
+		}
+
+		d.pos++
+	}
+
+	return d.parseNumber(d.s[start:], float)
+}
+
+func (d *decoder) parseNumber(text string, float bool) (any, error) {
+	if !float {
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, d.errorf("invalid number %q", text)
+	}
+
+	return f, nil
+}
+
+func (d *decoder) ident() string {
+	start := d.pos
+
+	for d.pos < len(d.s) && (isIdentStart(d.s[d.pos]) || isDigit(d.s[d.pos])) {
+		d.pos++
+	}
+
+	return d.s[start:d.pos]
+}
+
+func (d *decoder) identifier() (any, error) {
+	name := d.ident()
+
+	switch name {
+	case "true", "false":
+		return name == "true", nil
+	case "null", "nil":
+		return nil, nil
+	case "inf":
+		return math.Inf(1), nil
+	case "inf_neg":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+
+	// Typed containers, such as Array[int]([1, 2]), hold the same values as
+	// untyped ones.
+	if d.peek() == '[' && (name == "Array" || name == "Dictionary") {
+		if err := d.skipType(); err != nil {
+			return nil, err
+		}
+
+		if err := d.expect('('); err != nil {
+			return nil, err
+		}
+
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+
+		return v, d.expect(')')
+	}
+
+	if d.peek() != '(' {
+		return nil, d.errorf("unknown identifier %q", name)
+	}
+
+	d.pos++
+
+	if name == "Object" {
+		return d.object()
+	}
+
+	args, err := d.list(')')
+	if err != nil {
+		return nil, err
+	}
+
+	return construct(name, args)
+}
+
+// skipType skips the element types of a typed container.
+func (d *decoder) skipType() error {
+	depth := 0
+
+	for d.pos < len(d.s) {
+		switch d.s[d.pos] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+
+		d.pos++
+
+		if depth == 0 {
+			return nil
+		}
+	}
+
+	return d.errorf("unterminated type")
+}
+
+// list decodes comma separated values up to the closing character.
+func (d *decoder) list(end byte) ([]any, error) {
+	values := []any{}
+
+	for {
+		if d.peek() == end {
+			d.pos++
+
+			return values, nil
+		}
+
+		if len(values) > 0 {
+			if err := d.expect(','); err != nil {
+				return nil, err
+			}
+
+			// Trailing commas are allowed.
+			if d.peek() == end {
+				continue
+			}
+		}
+
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, v)
+	}
+}
+
+func (d *decoder) array() (any, error) {
+	return d.list(']')
+}
+
+func (d *decoder) dictionary() (any, error) {
+	entries, err := d.entries('}')
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// entries decodes comma separated key: value pairs up to the closing character.
+func (d *decoder) entries(end byte) (Dictionary, error) {
+	dict := Dictionary{}
+
+	for {
+		if d.peek() == end {
+			d.pos++
+
+			return dict, nil
+		}
+
+		if len(dict) > 0 {
+			if err := d.expect(','); err != nil {
+				return nil, err
+			}
+
+			if d.peek() == end {
+				continue
+			}
+		}
+
+		key, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := d.expect(':'); err != nil {
+			return nil, err
+		}
+
+		value, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+
+		dict = append(dict, KeyValue{Key: key, Value: value})
+	}
+}
+
+// object decodes Object(Class, "property": value, ...).
+func (d *decoder) object() (any, error) {
+	d.skipSpace()
+
+	class := d.ident()
+	if class == "" {
+		return nil, d.errorf("expected object class")
+	}
+
+	if d.peek() == ')' {
+		d.pos++
+
+		return Object{Class: class, Properties: Dictionary{}}, nil
+	}
+
+	if err := d.expect(','); err != nil {
+		return nil, err
+	}
+
+	properties, err := d.entries(')')
+	if err != nil {
+		return nil, err
+	}
+
+	return Object{Class: class, Properties: properties}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// ternary returns a if cond holds, otherwise b.
+func ternary[T any](cond bool, a, b T) T {
+	if cond {
+		return a
+	}
+
+	return b
+}
+
+// construct builds the value of a constructor call from its arguments.
+//
+//nolint:cyclop,funlen
+func construct(name string, args []any) (any, error) {
+	switch name {
+	case "PackedStringArray", "PoolStringArray":
+		return convertAll(name, args, func(v any) (string, bool) { s, ok := v.(string); return s, ok })
+	// Godot 3's pools hold 32-bit numbers.
+	case "PackedInt32Array", "PoolIntArray":
+		return convertAll(name, args, toInt32)
+	case "PackedInt64Array":
+		return convertAll(name, args, toInt)
+	case "PackedFloat32Array", "PoolRealArray":
+		return convertAll(name, args, func(v any) (float32, bool) { f, ok := toFloat(v); return float32(f), ok })
+	case "PackedFloat64Array":
+		return convertAll(name, args, toFloat)
+	case "PackedByteArray", "PoolByteArray":
+		return byteArray(name, args)
+	case "PackedVector2Array", "PoolVector2Array":
+		return groups(name, args, 2, func(f []float64) Vector2 { return Vector2{f[0], f[1]} })
+	case "PackedVector3Array", "PoolVector3Array":
+		return groups(name, args, 3, func(f []float64) Vector3 { return Vector3{f[0], f[1], f[2]} })
+	case "PackedVector4Array":
+		return groups(name, args, 4, func(f []float64) Vector4 { return Vector4{f[0], f[1], f[2], f[3]} })
+	case "PackedColorArray", "PoolColorArray":
+		return groups(name, args, 4, func(f []float64) Color { return Color{f[0], f[1], f[2], f[3]} })
+	case "Vector2":
+		return single(name, args, 2, func(f []float64) Vector2 { return Vector2{f[0], f[1]} })
+	case "Vector3":
+		return single(name, args, 3, func(f []float64) Vector3 { return Vector3{f[0], f[1], f[2]} })
+	case "Vector4":
+		return single(name, args, 4, func(f []float64) Vector4 { return Vector4{f[0], f[1], f[2], f[3]} })
+	case "Rect2":
+		return single(name, args, 4, func(f []float64) Rect2 { return Rect2{Vector2{f[0], f[1]}, Vector2{f[2], f[3]}} })
+	case "Vector2i":
+		return singleInt(name, args, 2, func(n []int64) Vector2i { return Vector2i{n[0], n[1]} })
+	case "Vector3i":
+		return singleInt(name, args, 3, func(n []int64) Vector3i { return Vector3i{n[0], n[1], n[2]} })
+	case "Vector4i":
+		return singleInt(name, args, 4, func(n []int64) Vector4i { return Vector4i{n[0], n[1], n[2], n[3]} })
+	case "Rect2i":
+		return singleInt(name, args, 4, func(n []int64) Rect2i { return Rect2i{Vector2i{n[0], n[1]}, Vector2i{n[2], n[3]}} })
+	case "Color":
+		// The alpha is optional.
+		if len(args) == 3 {
+			args = append(args, 1.0)
+		}
+
+		return single(name, args, 4, func(f []float64) Color { return Color{f[0], f[1], f[2], f[3]} })
+	case "NodePath":
+		// Godot 3's form of ^"path".
+		if len(args) == 1 {
+			if path, ok := args[0].(string); ok {
+				return NodePath(path), nil
+			}
+		}
+	case "SubResource", "ExtResource":
+		id, err := resourceID(name, args)
+		if err != nil {
+			return nil, err
+		}
+
+		return ternary[any](name == "SubResource", SubResource{ID: id}, ExtResource{ID: id}), nil
+	}
+
+	return Constructor{Name: name, Args: args}, nil
+}
+
+func toInt(v any) (int64, bool) {
+	n, ok := v.(int64)
+
+	return n, ok
+}
+
+func toInt32(v any) (int32, bool) {
+	n, ok := v.(int64)
+
+	return int32(n), ok && n >= math.MinInt32 && n <= math.MaxInt32
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	}
+
+	return 0, false
+}
+
+func convertAll[T any](name string, args []any, convert func(any) (T, bool)) ([]T, error) {
+	values := make([]T, 0, len(args))
+
+	for i, a := range args {
+		v, ok := convert(a)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s has an invalid element %d: %v", ErrSyntax, name, i, a)
+		}
+
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+func groups[T any](name string, args []any, size int, build func([]float64) T) ([]T, error) {
+	floats, err := convertAll(name, args, toFloat)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(floats)%size != 0 {
+		return nil, fmt.Errorf("%w: %s needs a multiple of %d numbers, got %d", ErrSyntax, name, size, len(floats))
+	}
+
+	values := make([]T, 0, len(floats)/size)
+
+	for i := 0; i < len(floats); i += size {
+		values = append(values, build(floats[i:i+size]))
+	}
+
+	return values, nil
+}
+
+func single[T any](name string, args []any, size int, build func([]float64) T) (any, error) {
+	if len(args) != size {
+		return nil, fmt.Errorf("%w: %s needs %d numbers, got %d", ErrSyntax, name, size, len(args))
+	}
+
+	values, err := groups(name, args, size, build)
+	if err != nil {
+		return nil, err
+	}
+
+	return values[0], nil
+}
+
+func singleInt[T any](name string, args []any, size int, build func([]int64) T) (any, error) {
+	if len(args) != size {
+		return nil, fmt.Errorf("%w: %s needs %d integers, got %d", ErrSyntax, name, size, len(args))
+	}
+
+	values, err := convertAll(name, args, toInt)
+	if err != nil {
+		return nil, err
+	}
+
+	return build(values), nil
+}
+
+// byteArray decodes a byte array, written as numbers, or by Godot 4.3 and later
+// as a single base64 string, which is decoded into Base64Bytes.
+func byteArray(name string, args []any) (any, error) {
+	if len(args) == 1 {
+		if s, ok := args[0].(string); ok {
+			data, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s has invalid base64: %w", ErrSyntax, name, err)
+			}
+
+			return Base64Bytes(data), nil
+		}
+	}
+
+	return convertAll(name, args, func(v any) (byte, bool) {
+		n, ok := v.(int64)
+
+		return byte(n), ok && n >= 0 && n <= math.MaxUint8
+	})
+}
+
+// resourceID returns the id of a resource reference, which is a string in
+// Godot 4 and an integer in Godot 3.
+func resourceID(name string, args []any) (string, error) {
+	if len(args) == 1 {
+		switch id := args[0].(type) {
+		case string:
+			return id, nil
+		case int64:
+			return strconv.FormatInt(id, 10), nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s needs a single id, got %v", ErrSyntax, name, args)
+}
@@ -0,0 +1,245 @@
+package variant
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format encodes a value in the text form Godot 4 writes. It accepts the
+// types Parse returns, as well as other Go integers, floats and maps, and
+// falls back to fmt's default format for anything else.
+func Format(v any) string {
+	return encoder{}.format(v)
+}
+
+// FormatGodot3 encodes a value in the text form Godot 3 writes: Pool*Array
+// rather than Packed*Array, spaces inside the parentheses of constructors and
+// the brackets of arrays, NodePath("...") and unquoted numeric resource IDs.
+// Types that Godot 3 doesn't have are written as Format writes them.
+func FormatGodot3(v any) string {
+	return encoder{godot3: true}.format(v)
+}
+
+// encoder writes values in the syntax of one major version of Godot.
+type encoder struct {
+	godot3 bool
+}
+
+//nolint:cyclop,funlen
+func (e encoder) format(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float32:
+		return formatFloat(float64(v))
+	case float64:
+		return formatFloat(v)
+	case string:
+		return quote(v)
+	case StringName:
+		return ternary(e.godot3, "", "&") + quote(string(v))
+	case NodePath:
+		return ternary(e.godot3, "NodePath("+quote(string(v))+")", "^"+quote(string(v)))
+	case []any:
+		return ternary(e.godot3, "[ "+join(v, e.format)+" ]", "["+join(v, e.format)+"]")
+	case []string:
+		return e.call(e.packed("String"), join(v, quote))
+	case []int32:
+		return e.call(ternary(e.godot3, "PoolIntArray", "PackedInt32Array"), join(v, func(n int32) string { return formatInt(int64(n)) }))
+	case []int64:
+		return e.call(ternary(e.godot3, "PoolIntArray", "PackedInt64Array"), join(v, formatInt))
+	case []float32:
+		return e.call(ternary(e.godot3, "PoolRealArray", "PackedFloat32Array"), join(v, formatFloat32))
+	case []float64:
+		return e.call(ternary(e.godot3, "PoolRealArray", "PackedFloat64Array"), join(v, formatComponent))
+	case []byte:
+		return e.call(e.packed("Byte"), join(v, func(b byte) string { return strconv.Itoa(int(b)) }))
+	case Base64Bytes:
+		if e.godot3 {
+			return e.format([]byte(v))
+		}
+
+		return "PackedByteArray(" + quote(base64.StdEncoding.EncodeToString(v)) + ")"
+	case []Vector2:
+		return e.call(e.packed("Vector2"), join(v, func(e Vector2) string { return components(e.X, e.Y) }))
+	case []Vector3:
+		return e.call(e.packed("Vector3"), join(v, func(e Vector3) string { return components(e.X, e.Y, e.Z) }))
+	case []Vector4:
+		return e.call("PackedVector4Array", join(v, func(e Vector4) string { return components(e.X, e.Y, e.Z, e.W) }))
+	case []Color:
+		return e.call(e.packed("Color"), join(v, func(e Color) string { return components(e.R, e.G, e.B, e.A) }))
+	case Vector2:
+		return e.call("Vector2", components(v.X, v.Y))
+	case Vector3:
+		return e.call("Vector3", components(v.X, v.Y, v.Z))
+	case Vector4:
+		return e.call("Vector4", components(v.X, v.Y, v.Z, v.W))
+	case Vector2i:
+		return e.call("Vector2i", join([]int64{v.X, v.Y}, formatInt))
+	case Vector3i:
+		return e.call("Vector3i", join([]int64{v.X, v.Y, v.Z}, formatInt))
+	case Vector4i:
+		return e.call("Vector4i", join([]int64{v.X, v.Y, v.Z, v.W}, formatInt))
+	case Color:
+		return e.call("Color", components(v.R, v.G, v.B, v.A))
+	case Rect2:
+		return e.call("Rect2", components(v.Position.X, v.Position.Y, v.Size.X, v.Size.Y))
+	case Rect2i:
+		return e.call("Rect2i", join([]int64{v.Position.X, v.Position.Y, v.Size.X, v.Size.Y}, formatInt))
+	case Dictionary:
+		return e.formatDictionary(v)
+	case map[string]any:
+		return e.formatMap(v)
+	case Object:
+		if len(v.Properties) == 0 {
+			return "Object(" + v.Class + ")"
+		}
+
+		parts := make([]string, len(v.Properties))
+
+		for i, kv := range v.Properties {
+			parts[i] = e.format(kv.Key) + ":" + e.format(kv.Value)
+		}
+
+		// Unlike dictionaries, objects are written on a single line.
+		return "Object(" + v.Class + "," + strings.Join(parts, ",") + ")"
+	case SubResource:
+		return e.call("SubResource", e.resourceID(v.ID))
+	case ExtResource:
+		return e.call("ExtResource", e.resourceID(v.ID))
+	case Constructor:
+		return e.call(v.Name, join(v.Args, e.format))
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+// call writes a constructor call. Godot 3 pads the arguments with spaces,
+// even when there are none.
+func (e encoder) call(name, args string) string {
+	if e.godot3 {
+		return name + "( " + args + " )"
+	}
+
+	return name + "(" + args + ")"
+}
+
+// packed returns the name of the array type of an element type.
+func (e encoder) packed(element string) string {
+	return ternary(e.godot3, "Pool", "Packed") + element + "Array"
+}
+
+// resourceID writes the ID of a resource. Godot 3 numbers resources, and
+// writes the IDs without quotes.
+func (e encoder) resourceID(id string) string {
+	if _, err := strconv.ParseInt(id, 10, 64); err == nil && e.godot3 {
+		return id
+	}
+
+	return quote(id)
+}
+
+// quote writes a string the way Godot does: only quotes and backslashes are
+// escaped, and newlines are kept as they are.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func formatInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// formatFloat writes a float so that it reads back as a float.
+func formatFloat(f float64) string {
+	s := formatComponent(f)
+
+	if strings.ContainsAny(s, ".en") {
+		return s
+	}
+
+	return s + ".0"
+}
+
+// formatComponent writes a float the way Godot writes the components of
+// vectors, without a fraction for whole numbers.
+func formatComponent(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "inf_neg"
+	case math.IsNaN(f):
+		return "nan"
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// formatFloat32 writes a 32-bit float with as few digits as read it back.
+func formatFloat32(f float32) string {
+	if math.IsInf(float64(f), 0) || math.IsNaN(float64(f)) {
+		return formatComponent(float64(f))
+	}
+
+	return strconv.FormatFloat(float64(f), 'g', -1, 32)
+}
+
+func components(f ...float64) string {
+	return join(f, formatComponent)
+}
+
+func join[T any](values []T, format func(T) string) string {
+	parts := make([]string, len(values))
+
+	for i, v := range values {
+		parts[i] = format(v)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func (e encoder) formatDictionary(d Dictionary) string {
+	// Godot 3 writes the braces of an empty dictionary on their own lines too.
+	if len(d) == 0 {
+		return ternary(e.godot3, "{\n\n}", "{}")
+	}
+
+	parts := make([]string, len(d))
+
+	for i, kv := range d {
+		parts[i] = e.format(kv.Key) + ": " + e.format(kv.Value)
+	}
+
+	// Godot puts each entry of a dictionary on its own line.
+	return "{\n" + strings.Join(parts, ",\n") + "\n}"
+}
+
+func (e encoder) formatMap(m map[string]any) string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	d := make(Dictionary, len(keys))
+
+	for i, k := range keys {
+		d[i] = KeyValue{Key: k, Value: m[k]}
+	}
+
+	return e.formatDictionary(d)
+}
@@ -0,0 +1,100 @@
+// Package variant decodes and encodes the text form of Godot's Variant values,
+// as written to project.godot, export_presets.cfg and resource files.
+//
+// Values are decoded into plain Go types where there is an obvious one:
+//
+//	null                          nil
+//	true, false                   bool
+//	42                            int64
+//	4.2, inf, nan                 float64
+//	"text"                        string
+//	[1, "a"]                      []any
+//	PackedStringArray("a")        []string
+//	PackedInt32Array(1, 2)        []int32
+//	PackedInt64Array(1, 2)        []int64
+//	PackedFloat32Array(1.5)       []float32
+//	PackedFloat64Array(1.5)       []float64
+//	PackedByteArray(1, 2)         []byte
+//
+// Everything else is decoded into the types of this package, so that each
+// value is written back as the same type. Godot 3 names, such as
+// PoolStringArray, are accepted as well.
+package variant
+
+import (
+	"reflect"
+)
+
+// StringName is an interned Godot string, written as &"name".
+type StringName string
+
+// NodePath is a path to a node in a scene tree, written as ^"path".
+type NodePath string
+
+// Base64Bytes is a PackedByteArray written as a base64 string, as Godot 4.3
+// and later write them. A []byte is written as a list of numbers instead, which
+// every version of Godot reads.
+type Base64Bytes []byte
+
+type Vector2 struct{ X, Y float64 }
+
+type Vector2i struct{ X, Y int64 }
+
+type Vector3 struct{ X, Y, Z float64 }
+
+type Vector3i struct{ X, Y, Z int64 }
+
+type Vector4 struct{ X, Y, Z, W float64 }
+
+type Vector4i struct{ X, Y, Z, W int64 }
+
+// Color is an RGBA color with components usually between 0 and 1.
+type Color struct{ R, G, B, A float64 }
+
+type Rect2 struct{ Position, Size Vector2 }
+
+type Rect2i struct{ Position, Size Vector2i }
+
+// KeyValue is a single entry of a Dictionary.
+type KeyValue struct {
+	Key   any
+	Value any
+}
+
+// Dictionary is a Godot Dictionary. Keys can be any value, so the entries are
+// kept in a slice, in the order they were written.
+type Dictionary []KeyValue
+
+// Get returns the value of a key.
+func (d Dictionary) Get(key any) (any, bool) {
+	for _, kv := range d {
+		if reflect.DeepEqual(kv.Key, key) {
+			return kv.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Object is an inline object, such as the input events of an input action.
+type Object struct {
+	Class      string
+	Properties Dictionary
+}
+
+// SubResource refers to a resource defined in the same file.
+type SubResource struct {
+	ID string
+}
+
+// ExtResource refers to a resource loaded from another file.
+type ExtResource struct {
+	ID string
+}
+
+// Constructor is any other value written as a constructor call, such as
+// Transform3D(...) or Quaternion(...), with its decoded arguments.
+type Constructor struct {
+	Name string
+	Args []any
+}
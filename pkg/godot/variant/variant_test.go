@@ -0,0 +1,228 @@
+package variant_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/ruffel/godotreleaser/pkg/godot/variant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input string
+		want  any
+	}{
+		{`null`, nil},
+		{`true`, true},
+		{`false`, false},
+		{`42`, int64(42)},
+		{`-7`, int64(-7)},
+		{`0.5`, 0.5},
+		{`1e-05`, 1e-05},
+		{`-2.5e+3`, -2500.0},
+		{`"My Game"`, "My Game"},
+		{`""`, ""},
+		{`"say \"hi\" \\ \té"`, "say \"hi\" \\ \té"},
+		{"\"line1\nline2\"", "line1\nline2"},
+		{`&"ui_accept"`, variant.StringName("ui_accept")},
+		{`^"Player/Sprite2D"`, variant.NodePath("Player/Sprite2D")},
+		{`Vector2(1, 2.5)`, variant.Vector2{X: 1, Y: 2.5}},
+		{`Vector2( 1, 2 )`, variant.Vector2{X: 1, Y: 2}},
+		{`Vector2i(1920, 1080)`, variant.Vector2i{X: 1920, Y: 1080}},
+		{`Vector3(1, 2, 3)`, variant.Vector3{X: 1, Y: 2, Z: 3}},
+		{`Vector3i(-1, 0, 1)`, variant.Vector3i{X: -1, Z: 1}},
+		{`Vector4(1, 2, 3, 4)`, variant.Vector4{X: 1, Y: 2, Z: 3, W: 4}},
+		{`Vector4i(1, 2, 3, 4)`, variant.Vector4i{X: 1, Y: 2, Z: 3, W: 4}},
+		{`Color(0.3, 0.3, 0.3, 1)`, variant.Color{R: 0.3, G: 0.3, B: 0.3, A: 1}},
+		{`Color(1, 0, 0)`, variant.Color{R: 1, A: 1}},
+		{`Rect2(0, 0, 64, 32)`, variant.Rect2{Size: variant.Vector2{X: 64, Y: 32}}},
+		{`Rect2i(1, 2, 3, 4)`, variant.Rect2i{Position: variant.Vector2i{X: 1, Y: 2}, Size: variant.Vector2i{X: 3, Y: 4}}},
+		{`[]`, []any{}},
+		{`[1, "two", [3], ]`, []any{int64(1), "two", []any{int64(3)}}},
+		{"[1, ; comment\n2 # comment\n]", []any{int64(1), int64(2)}},
+		{`Array[int]([1, 2])`, []any{int64(1), int64(2)}},
+		{`Array[StringName]([&"a"])`, []any{variant.StringName("a")}},
+		{`PackedStringArray("foo", "bar")`, []string{"foo", "bar"}},
+		{`PackedStringArray()`, []string{}},
+		{`PoolStringArray( "foo" )`, []string{"foo"}},
+		{`PackedInt32Array(1, 2, 3)`, []int32{1, 2, 3}},
+		{`PackedInt64Array(1, 2, 3)`, []int64{1, 2, 3}},
+		{`PoolIntArray( 4 )`, []int32{4}},
+		{`PackedFloat32Array(1, 2.5)`, []float32{1, 2.5}},
+		{`PackedFloat64Array(1, 2.5)`, []float64{1, 2.5}},
+		{`PoolRealArray( 0.5 )`, []float32{0.5}},
+		{`PackedByteArray(1, 255)`, []byte{1, 255}},
+		{`PackedByteArray("AQL/")`, variant.Base64Bytes{1, 2, 255}},
+		{`PackedVector2Array(0, 0, 1, 2)`, []variant.Vector2{{}, {X: 1, Y: 2}}},
+		{`PackedVector3Array(1, 2, 3)`, []variant.Vector3{{X: 1, Y: 2, Z: 3}}},
+		{`PackedColorArray(1, 1, 1, 1)`, []variant.Color{{R: 1, G: 1, B: 1, A: 1}}},
+		{`SubResource("Theme_x2c4f")`, variant.SubResource{ID: "Theme_x2c4f"}},
+		{`SubResource( 3 )`, variant.SubResource{ID: "3"}},
+		{`ExtResource("1_abcde")`, variant.ExtResource{ID: "1_abcde"}},
+		{`Transform2D(1, 0, 0, 1, 0, 0)`, variant.Constructor{
+			Name: "Transform2D",
+			Args: []any{int64(1), int64(0), int64(0), int64(1), int64(0), int64(0)},
+		}},
+		{`{}`, variant.Dictionary{}},
+		{`{ "foo": "bar", 2: [true] }`, variant.Dictionary{
+			{Key: "foo", Value: "bar"},
+			{Key: int64(2), Value: []any{true}},
+		}},
+		{`Dictionary[String, int]({"a": 1})`, variant.Dictionary{{Key: "a", Value: int64(1)}}},
+		{`Object(InputEventKey,"resource_name":"","keycode":0,"unicode":32,"echo":false,"script":null)`, variant.Object{
+			Class: "InputEventKey",
+			Properties: variant.Dictionary{
+				{Key: "resource_name", Value: ""},
+				{Key: "keycode", Value: int64(0)},
+				{Key: "unicode", Value: int64(32)},
+				{Key: "echo", Value: false},
+				{Key: "script", Value: nil},
+			},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := variant.Parse(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParse_InputAction(t *testing.T) {
+	t.Parallel()
+
+	// As written by Godot 4 to the [input] section of project.godot.
+	got, err := variant.Parse(heredoc.Doc(`
+		{
+		"deadzone": 0.5,
+		"events": [Object(InputEventKey,"resource_local_to_scene":false,"physical_keycode":32,"pressed":false,"script":null)
+		, Object(InputEventJoypadButton,"device":-1,"button_index":0,"pressure":0.0,"pressed":false,"script":null)
+		]
+		}
+	`))
+	require.NoError(t, err)
+
+	action, ok := got.(variant.Dictionary)
+	require.True(t, ok)
+
+	deadzone, ok := action.Get("deadzone")
+	require.True(t, ok)
+	assert.InDelta(t, 0.5, deadzone, 0)
+
+	events, ok := action.Get("events")
+	require.True(t, ok)
+	require.Len(t, events, 2)
+	assert.Equal(t, "InputEventJoypadButton", events.([]any)[1].(variant.Object).Class) //nolint:forcetypeassert
+}
+
+func TestParse_Special(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]float64{"inf": math.Inf(1), "inf_neg": math.Inf(-1), "-inf": math.Inf(-1)} {
+		got, err := variant.Parse(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	got, err := variant.Parse("nan")
+	require.NoError(t, err)
+	assert.True(t, math.IsNaN(got.(float64))) //nolint:forcetypeassert
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		``,
+		`foo`,
+		`"unterminated`,
+		`[1, 2`,
+		`{"a" 1}`,
+		`Vector2(1)`,
+		`Vector2i(1.5, 2)`,
+		`PackedStringArray(1)`,
+		`PackedVector2Array(1, 2, 3)`,
+		`1 2`,
+	} {
+		_, err := variant.Parse(input)
+		assert.ErrorIs(t, err, variant.ErrSyntax, input)
+	}
+}
+
+func TestFormat_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`null`,
+		`true`,
+		`42`,
+		`1.0`,
+		`0.25`,
+		`"say \"hi\" \\"`,
+		`&"ui_accept"`,
+		`^"Player/Sprite2D"`,
+		`[1, "two", [3.5]]`,
+		`PackedStringArray("foo", "bar")`,
+		`PackedStringArray()`,
+		`PackedInt32Array(1, -2, 2147483647)`,
+		`PackedInt64Array(1, -2, 9223372036854775807)`,
+		`PackedFloat32Array(0, 0.5, 0.1, -3.25)`,
+		`PackedFloat64Array(0, 0.5, 0.1, -3.25)`,
+		`PackedByteArray(1, 2, 3)`,
+		`PackedByteArray()`,
+		`PackedByteArray("AQID")`,
+		`PackedVector2Array(0, 0, 1, 2.5)`,
+		`PackedVector3Array(1, 2, 3)`,
+		`PackedVector4Array(1, 2, 3, 4)`,
+		`PackedColorArray(1, 0.5, 0, 1)`,
+		`Vector2(1, 2.5)`,
+		`Vector3i(1, 2, 3)`,
+		`Color(1, 0.5, 0, 1)`,
+		`Rect2(0, 0, 64, 32)`,
+		`SubResource("Theme_x2c4f")`,
+		`Transform2D(1, 0, 0, 1, 0, 0)`,
+		"{\n\"deadzone\": 0.5,\n\"events\": []\n}",
+		`Object(InputEventKey,"pressed":false,"script":null)`,
+	} {
+		v, err := variant.Parse(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, input, variant.Format(v))
+	}
+}
+
+func TestFormatGodot3_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`"ui_accept"`,
+		`NodePath("Player/Sprite")`,
+		`[ 1, "two", [ 3.5 ] ]`,
+		`PoolStringArray( "foo", "bar" )`,
+		`PoolStringArray(  )`,
+		`PoolIntArray( 1, 2 )`,
+		`PoolRealArray( 0.5, 1, 0.1 )`,
+		`PoolByteArray( 1, 2, 3 )`,
+		`PoolVector2Array( 0, 0, 1, 2 )`,
+		`PoolVector3Array( 1, 2, 3 )`,
+		`PoolColorArray( 1, 0.5, 0, 1 )`,
+		`Vector2( 1, 2.5 )`,
+		`Color( 1, 0.5, 0, 1 )`,
+		`SubResource( 1 )`,
+		`ExtResource( 2 )`,
+		"{\n\n}",
+		"{\n\"deadzone\": 0.5,\n\"events\": [  ]\n}",
+	} {
+		v, err := variant.Parse(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, input, variant.FormatGodot3(v))
+	}
+}